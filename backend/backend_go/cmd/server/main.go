@@ -2,33 +2,70 @@ package main
 
 import (
 	"log"
-	"path/filepath"
+	"time"
 
+	"pengyou-chinese/backend/internal/config"
 	"pengyou-chinese/backend/internal/handlers"
 	"pengyou-chinese/backend/internal/middleware"
 	"pengyou-chinese/backend/internal/service"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
 	// Set Gin to release mode in production
 	gin.SetMode(gin.ReleaseMode)
 
-	// Initialize database service
-	dbPath := filepath.Join(".", "words.db")
-	db, err := service.NewDBService(dbPath)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize the storage backend selected by the database URL
+	db, err := service.OpenStore(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	// Initialize handlers
-	dashboardHandler := handlers.NewDashboardHandler(db)
-	wordsHandler := handlers.NewWordsHandler(db)
-	groupsHandler := handlers.NewGroupsHandler(db)
-	studyHandler := handlers.NewStudyHandler(db)
+	// Take scheduled snapshots of SQLite databases
+	snapshots := service.Snapshots{
+		Dir:    cfg.BackupDir,
+		Prefix: service.SnapshotPrefix(cfg.DatabaseURL),
+		Keep:   cfg.BackupKeep,
+	}
+	if cfg.BackupInterval > 0 {
+		if !db.SupportsSnapshots() {
+			log.Printf("Scheduled backups are only supported for SQLite databases, BACKUP_INTERVAL is ignored")
+		} else {
+			go func() {
+				ticker := time.NewTicker(cfg.BackupInterval)
+				defer ticker.Stop()
+				for range ticker.C {
+					snapshot, err := snapshots.Take(db)
+					if err != nil {
+						log.Printf("Failed to back up the database: %v", err)
+						continue
+					}
+					log.Printf("Backed up the database to %s", snapshot.Path)
+				}
+			}()
+		}
+	}
+
+	// Periodically close study sessions that were left open
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := db.ExpireStaleSessions(cfg.StaleSessionTimeout); err != nil {
+				log.Printf("Failed to expire stale study sessions: %v", err)
+			}
+		}
+	}()
+
+	// Report validation errors by the request's field names
+	middleware.RegisterFieldNames()
 
 	// Create a default Gin router
 	router := gin.Default()
@@ -37,12 +74,13 @@ func main() {
 	router.Use(middleware.RequestIDMiddleware())
 	router.Use(middleware.Logger())
 	router.Use(middleware.ErrorHandler())
+	router.NoRoute(middleware.NotFound())
 
 	// Add CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, X-Request-ID, X-Session-Token")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -53,33 +91,7 @@ func main() {
 	})
 
 	// API routes
-	api := router.Group("/api")
-	{
-		// Dashboard routes
-		api.GET("/dashboard/last_study_session", dashboardHandler.GetLastStudySession)
-		api.GET("/dashboard/study_progress", dashboardHandler.GetStudyProgress)
-		api.GET("/dashboard/quick-stats", dashboardHandler.GetQuickStats)
-
-		// Words routes
-		api.GET("/words", wordsHandler.GetWords)
-		api.GET("/words/:id", wordsHandler.GetWord)
-		api.POST("/study_sessions/:id/words/:word_id/review", wordsHandler.AddWordReview)
-
-		// Groups routes
-		api.GET("/groups", groupsHandler.GetGroups)
-		api.GET("/groups/:id", groupsHandler.GetGroup)
-		api.GET("/groups/:id/words", groupsHandler.GetGroupWords)
-
-		// Study sessions routes
-		api.GET("/study_sessions", studyHandler.GetStudySessions)
-		api.GET("/study_sessions/:id", studyHandler.GetStudySession)
-		api.GET("/study_sessions/:id/words", studyHandler.GetStudySessionWords)
-		api.POST("/study_sessions", studyHandler.CreateStudySession)
-
-		// Study activities routes
-		api.GET("/study_activities/:id", studyHandler.GetStudyActivity)
-		api.GET("/study_activities/:id/study_sessions", studyHandler.GetStudyActivitySessions)
-	}
+	handlers.RegisterRoutes(router.Group("/api"), db, snapshots)
 
 	// Start the server
 	log.Printf("Starting server on :8080")
@@ -0,0 +1,16 @@
+// Package db embeds the SQL migrations and JSON seed files so they ship with the server binary.
+package db
+
+import "embed"
+
+// Migrations holds the SQL migration files, applied in order of their file name.
+// The SQLite migrations are in migrations/ and their PostgreSQL ports, under
+// the same file names, in migrations/postgres/.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var Migrations embed.FS
+
+// Seeds holds the JSON seed files used to populate a fresh database
+//
+//go:embed seeds/*.json
+var Seeds embed.FS
@@ -0,0 +1,7 @@
+-- Drop the initial schema, referencing tables first
+DROP TABLE IF EXISTS word_review_items;
+DROP TABLE IF EXISTS study_activities;
+DROP TABLE IF EXISTS study_sessions;
+DROP TABLE IF EXISTS words_groups;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS words;
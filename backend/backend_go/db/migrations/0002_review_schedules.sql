@@ -0,0 +1,12 @@
+-- Create word_review_schedules table holding the spaced-repetition state of each word
+CREATE TABLE IF NOT EXISTS word_review_schedules (
+    word_id INTEGER PRIMARY KEY,
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    due_at DATETIME NOT NULL,
+    last_reviewed_at DATETIME NOT NULL,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_word_review_schedules_due_at ON word_review_schedules(due_at);
@@ -0,0 +1,2 @@
+-- Allow duplicate word-group memberships again. Duplicates removed by the up migration are not restored.
+DROP INDEX IF EXISTS idx_words_groups_word_id_group_id;
@@ -0,0 +1,12 @@
+-- Restore the placeholder study_activities table. The catalog's rows are not kept.
+DROP INDEX IF EXISTS idx_study_sessions_study_activity_id;
+DROP TABLE IF EXISTS study_activities;
+
+CREATE TABLE study_activities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    study_session_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
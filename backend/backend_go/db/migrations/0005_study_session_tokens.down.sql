@@ -0,0 +1 @@
+DROP TABLE IF EXISTS study_session_tokens;
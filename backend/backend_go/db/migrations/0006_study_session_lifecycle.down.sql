@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_study_sessions_status;
+ALTER TABLE study_sessions DROP COLUMN ended_at;
+ALTER TABLE study_sessions DROP COLUMN status;
@@ -0,0 +1,5 @@
+-- Track whether a study session is active, finished or abandoned and when it ended
+ALTER TABLE study_sessions ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE study_sessions ADD COLUMN ended_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_status ON study_sessions(status);
@@ -0,0 +1 @@
+ALTER TABLE word_review_items DROP COLUMN response_ms;
@@ -0,0 +1,4 @@
+ALTER TABLE word_review_items DROP COLUMN grade;
+ALTER TABLE word_review_items DROP COLUMN hint_used;
+ALTER TABLE word_review_items DROP COLUMN expected_answer;
+ALTER TABLE word_review_items DROP COLUMN answer;
@@ -0,0 +1,5 @@
+-- Record what the learner answered, what was expected, hint usage and a 0-5 grade per review
+ALTER TABLE word_review_items ADD COLUMN answer TEXT;
+ALTER TABLE word_review_items ADD COLUMN expected_answer TEXT;
+ALTER TABLE word_review_items ADD COLUMN hint_used BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE word_review_items ADD COLUMN grade INTEGER CHECK (grade BETWEEN 0 AND 5);
@@ -0,0 +1,29 @@
+-- Full-text search index over words, kept in sync with triggers.
+-- Requires SQLite built with FTS5 (go build -tags sqlite_fts5).
+CREATE VIRTUAL TABLE IF NOT EXISTS words_fts USING fts5(
+    japanese,
+    romaji,
+    english,
+    content = 'words',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO words_fts (words_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS words_fts_after_insert AFTER INSERT ON words BEGIN
+    INSERT INTO words_fts (rowid, japanese, romaji, english)
+    VALUES (new.id, new.japanese, new.romaji, new.english);
+END;
+
+CREATE TRIGGER IF NOT EXISTS words_fts_after_delete AFTER DELETE ON words BEGIN
+    INSERT INTO words_fts (words_fts, rowid, japanese, romaji, english)
+    VALUES ('delete', old.id, old.japanese, old.romaji, old.english);
+END;
+
+CREATE TRIGGER IF NOT EXISTS words_fts_after_update AFTER UPDATE ON words BEGIN
+    INSERT INTO words_fts (words_fts, rowid, japanese, romaji, english)
+    VALUES ('delete', old.id, old.japanese, old.romaji, old.english);
+    INSERT INTO words_fts (rowid, japanese, romaji, english)
+    VALUES (new.id, new.japanese, new.romaji, new.english);
+END;
@@ -0,0 +1,61 @@
+-- Create words table
+CREATE TABLE IF NOT EXISTS words (
+    id BIGSERIAL PRIMARY KEY,
+    japanese TEXT NOT NULL,
+    romaji TEXT NOT NULL,
+    english TEXT NOT NULL,
+    parts TEXT -- JSON field
+);
+
+-- Create groups table
+CREATE TABLE IF NOT EXISTS groups (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- Create words_groups join table
+CREATE TABLE IF NOT EXISTS words_groups (
+    id BIGSERIAL PRIMARY KEY,
+    word_id BIGINT NOT NULL,
+    group_id BIGINT NOT NULL,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Create study_sessions table.
+-- Timestamps are stored in UTC to the second, like SQLite's CURRENT_TIMESTAMP.
+CREATE TABLE IF NOT EXISTS study_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    group_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT date_trunc('second', CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    study_activity_id BIGINT NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Create study_activities table
+CREATE TABLE IF NOT EXISTS study_activities (
+    id BIGSERIAL PRIMARY KEY,
+    study_session_id BIGINT NOT NULL,
+    group_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT date_trunc('second', CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Create word_review_items table
+CREATE TABLE IF NOT EXISTS word_review_items (
+    id BIGSERIAL PRIMARY KEY,
+    word_id BIGINT NOT NULL,
+    study_session_id BIGINT NOT NULL,
+    correct BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT date_trunc('second', CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_words_groups_word_id ON words_groups(word_id);
+CREATE INDEX IF NOT EXISTS idx_words_groups_group_id ON words_groups(group_id);
+CREATE INDEX IF NOT EXISTS idx_study_sessions_group_id ON study_sessions(group_id);
+CREATE INDEX IF NOT EXISTS idx_word_review_items_word_id ON word_review_items(word_id);
+CREATE INDEX IF NOT EXISTS idx_word_review_items_study_session_id ON word_review_items(study_session_id);
@@ -0,0 +1,8 @@
+-- Remove duplicate word-group memberships, keeping the oldest row
+DELETE FROM words_groups
+WHERE id NOT IN (
+    SELECT MIN(id) FROM words_groups GROUP BY word_id, group_id
+);
+
+-- Prevent a word from being added to the same group twice
+CREATE UNIQUE INDEX IF NOT EXISTS idx_words_groups_word_id_group_id ON words_groups(word_id, group_id);
@@ -0,0 +1,15 @@
+-- Rebuild study_activities as a catalog of launchable activities.
+-- The old table only held placeholder rows linking sessions and groups, so its rows are not kept.
+DROP TABLE IF EXISTS study_activities;
+
+CREATE TABLE study_activities (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    thumbnail_url TEXT NOT NULL DEFAULT '',
+    launch_url TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT date_trunc('second', CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_study_activity_id ON study_sessions(study_activity_id);
@@ -0,0 +1,10 @@
+-- Create study_session_tokens table holding the short-lived tokens handed to launched study apps
+CREATE TABLE IF NOT EXISTS study_session_tokens (
+    token TEXT PRIMARY KEY,
+    study_session_id BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT date_trunc('second', CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    FOREIGN KEY (study_session_id) REFERENCES study_sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_study_session_tokens_study_session_id ON study_session_tokens(study_session_id);
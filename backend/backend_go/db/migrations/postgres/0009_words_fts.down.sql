@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_words_search;
+ALTER TABLE words DROP COLUMN search;
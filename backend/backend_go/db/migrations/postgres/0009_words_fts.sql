@@ -0,0 +1,10 @@
+-- Full-text search index over words, the counterpart of SQLite's FTS5 table.
+-- Japanese and romaji are weighted above English; the simple configuration
+-- keeps words unstemmed so that prefix matching behaves like FTS5.
+ALTER TABLE words ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', japanese), 'A') ||
+    setweight(to_tsvector('simple', romaji), 'A') ||
+    setweight(to_tsvector('simple', english), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_words_search ON words USING GIN (search);
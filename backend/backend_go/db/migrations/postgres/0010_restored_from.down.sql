@@ -0,0 +1,4 @@
+DROP INDEX idx_word_review_items_restored_from;
+DROP INDEX idx_study_sessions_restored_from;
+ALTER TABLE word_review_items DROP COLUMN restored_from;
+ALTER TABLE study_sessions DROP COLUMN restored_from;
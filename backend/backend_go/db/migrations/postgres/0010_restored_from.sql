@@ -0,0 +1,6 @@
+-- Record which backup archive a restored study session or review came from, as
+-- <archive id>/<archived id>, so that restoring the archive again skips it
+ALTER TABLE study_sessions ADD COLUMN restored_from TEXT;
+ALTER TABLE word_review_items ADD COLUMN restored_from TEXT;
+CREATE UNIQUE INDEX idx_study_sessions_restored_from ON study_sessions (restored_from);
+CREATE UNIQUE INDEX idx_word_review_items_restored_from ON word_review_items (restored_from);
@@ -0,0 +1,618 @@
+// Package anki reads and writes Anki deck packages (.apkg): zip archives
+// holding the deck's collection as a SQLite database in the schema 11 layout
+// that every Anki version since 2.1 can import.
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrInvalidPackage is returned for files that are not Anki packages this
+// package can read
+var ErrInvalidPackage = errors.New("invalid Anki package")
+
+// MaxCollectionSize is the largest collection read from a package once
+// decompressed, in bytes, so that a small package cannot fill the disk
+const MaxCollectionSize = 256 << 20
+
+// Card types, which tell how far a card has been studied
+const (
+	CardNew        = 0
+	CardLearning   = 1
+	CardReview     = 2
+	CardRelearning = 3
+)
+
+// Review answers, from forgotten to easy
+const (
+	EaseAgain = 1
+	EaseHard  = 2
+	EaseGood  = 3
+	EaseEasy  = 4
+)
+
+// Card is the scheduling state of a note's card
+type Card struct {
+	Type int
+	// Interval is the number of days until the next review of a review card
+	Interval int
+	// Factor is the ease factor in permille, e.g. 2500 for 2.5
+	Factor int
+	Reps   int
+	Lapses int
+	// Due is when the card is next shown, unset for new cards
+	Due time.Time
+	// LastReview is when the card was last answered, if it ever was
+	LastReview time.Time
+}
+
+// Review is an answer to a card
+type Review struct {
+	Time time.Time
+	Ease int
+	// Interval and LastInterval are the card's interval in days after and before the answer
+	Interval     int
+	LastInterval int
+	Factor       int
+	Duration     time.Duration
+	// Learning marks answers given while the card was new or being learned
+	Learning bool
+}
+
+// Note is a note read from a package, with the card that is studied first
+type Note struct {
+	ID int64
+	// FieldNames and Fields hold the names and HTML values of the note's
+	// fields in the order of its note type
+	FieldNames []string
+	Fields     []string
+	Deck       string
+	Card       Card
+}
+
+// ExportNote is a note written to a package. Its fields are plain text.
+type ExportNote struct {
+	// GUID identifies the note across exports so that Anki updates it when it
+	// is imported again rather than adding a copy
+	GUID    string
+	Fields  []string
+	Card    Card
+	Reviews []Review
+}
+
+// Deck is the content of a package written by WritePackage. All its notes
+// share a note type with the given fields and a card showing the first field
+// on the front and the back fields below it on the back.
+type Deck struct {
+	Name       string
+	NoteType   string
+	FieldNames []string
+	BackFields []string
+	Notes      []ExportNote
+}
+
+// ReadPackage reads the notes of a package. Notes of every note type are
+// returned; media files are ignored.
+func ReadPackage(r io.ReaderAt, size int64) ([]Note, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+
+	// Packages from Anki 2.1 carry collection.anki21 next to a legacy
+	// collection.anki2 placeholder. Packages exported without "Support older
+	// Anki versions" only have the compressed collection.anki21b.
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	collection := files["collection.anki21"]
+	if collection == nil {
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, fmt.Errorf(`%w: re-export the deck from Anki with "Support older Anki versions" checked`, ErrInvalidPackage)
+		}
+		return nil, fmt.Errorf("%w: no collection found", ErrInvalidPackage)
+	}
+
+	path, err := extractCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("error opening Anki collection: %v", err)
+	}
+	defer db.Close()
+
+	notes, err := readNotes(db)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	return notes, nil
+}
+
+// extractCollection copies a collection out of a package into a temporary file
+// for SQLite to open, returning its path. Collections larger than
+// MaxCollectionSize are rejected, whatever size the zip header claims.
+func extractCollection(file *zip.File) (string, error) {
+	tooLarge := fmt.Errorf("%w: the collection is larger than %d MB", ErrInvalidPackage, MaxCollectionSize>>20)
+	if file.UncompressedSize64 > MaxCollectionSize {
+		return "", tooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "anki-*.db")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file: %v", err)
+	}
+	defer dst.Close()
+
+	n, err := io.Copy(dst, io.LimitReader(src, MaxCollectionSize+1))
+	if err == nil && n > MaxCollectionSize {
+		err = tooLarge
+	} else if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// readNotes reads the notes of a collection with their first card
+func readNotes(db *sql.DB) ([]Note, error) {
+	var created int64
+	var modelsJSON, decksJSON string
+	if err := db.QueryRow("SELECT crt, models, decks FROM col").Scan(&created, &modelsJSON, &decksJSON); err != nil {
+		return nil, fmt.Errorf("error reading collection: %v", err)
+	}
+
+	var noteTypes map[string]struct {
+		Flds []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &noteTypes); err != nil {
+		return nil, fmt.Errorf("error reading note types: %v", err)
+	}
+	var decks map[string]struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return nil, fmt.Errorf("error reading decks: %v", err)
+	}
+
+	// The card with the lowest ordinal stands for its note. Cards moved into a
+	// filtered deck still belong to their original deck.
+	rows, err := db.Query(`
+		SELECT n.id, n.mid, n.flds, CASE WHEN c.odid != 0 THEN c.odid ELSE c.did END,
+			c.type, c.queue, CASE WHEN c.odid != 0 THEN c.odue ELSE c.due END,
+			c.ivl, c.factor, c.reps, c.lapses, COALESCE(r.last_review, 0)
+		FROM notes n
+		JOIN cards c ON c.id = (SELECT id FROM cards WHERE nid = n.id ORDER BY ord, id LIMIT 1)
+		LEFT JOIN (SELECT cid, MAX(id) AS last_review FROM revlog GROUP BY cid) r ON r.cid = c.id
+		ORDER BY n.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading notes: %v", err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		var note Note
+		var noteType, deck, due, lastReview int64
+		var queue int
+		var fields string
+		err := rows.Scan(&note.ID, &noteType, &fields, &deck, &note.Card.Type, &queue, &due,
+			&note.Card.Interval, &note.Card.Factor, &note.Card.Reps, &note.Card.Lapses, &lastReview)
+		if err != nil {
+			return nil, fmt.Errorf("error reading note: %v", err)
+		}
+
+		note.Fields = strings.Split(fields, "\x1f")
+		note.FieldNames = make([]string, len(note.Fields))
+		for _, field := range noteTypes[strconv.FormatInt(noteType, 10)].Flds {
+			if field.Ord >= 0 && field.Ord < len(note.FieldNames) {
+				note.FieldNames[field.Ord] = field.Name
+			}
+		}
+		note.Deck = decks[strconv.FormatInt(deck, 10)].Name
+
+		// Review cards and cards learned over several days (queue 3) are due on
+		// a day counted from the collection's creation, other learning cards at
+		// a time
+		switch {
+		case note.Card.Type == CardReview || queue == 3:
+			note.Card.Due = time.Unix(created, 0).UTC().AddDate(0, 0, int(due))
+		case note.Card.Type == CardLearning || note.Card.Type == CardRelearning:
+			note.Card.Due = time.Unix(due, 0).UTC()
+		}
+		if lastReview > 0 {
+			note.Card.LastReview = time.UnixMilli(lastReview).UTC()
+		}
+
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading notes: %v", err)
+	}
+
+	return notes, nil
+}
+
+var (
+	breakTags = regexp.MustCompile(`(?i)<br\s*/?>|</?div[^>]*>|</?p[^>]*>`)
+	otherTags = regexp.MustCompile(`<[^>]*>|\[sound:[^\]]*\]`)
+	spaces    = regexp.MustCompile(`\s+`)
+)
+
+// PlainText converts the HTML of a note field to plain text, dropping
+// formatting, images and sound references
+func PlainText(field string) string {
+	text := breakTags.ReplaceAllString(field, " ")
+	text = otherTags.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(html.UnescapeString(text), "\u00a0", " ")
+	return strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+}
+
+// schema creates the tables of a schema 11 collection
+const schema = `
+CREATE TABLE col (
+	id integer PRIMARY KEY,
+	crt integer NOT NULL,
+	mod integer NOT NULL,
+	scm integer NOT NULL,
+	ver integer NOT NULL,
+	dty integer NOT NULL,
+	usn integer NOT NULL,
+	ls integer NOT NULL,
+	conf text NOT NULL,
+	models text NOT NULL,
+	decks text NOT NULL,
+	dconf text NOT NULL,
+	tags text NOT NULL
+);
+CREATE TABLE notes (
+	id integer PRIMARY KEY,
+	guid text NOT NULL,
+	mid integer NOT NULL,
+	mod integer NOT NULL,
+	usn integer NOT NULL,
+	tags text NOT NULL,
+	flds text NOT NULL,
+	sfld integer NOT NULL,
+	csum integer NOT NULL,
+	flags integer NOT NULL,
+	data text NOT NULL
+);
+CREATE TABLE cards (
+	id integer PRIMARY KEY,
+	nid integer NOT NULL,
+	did integer NOT NULL,
+	ord integer NOT NULL,
+	mod integer NOT NULL,
+	usn integer NOT NULL,
+	type integer NOT NULL,
+	queue integer NOT NULL,
+	due integer NOT NULL,
+	ivl integer NOT NULL,
+	factor integer NOT NULL,
+	reps integer NOT NULL,
+	lapses integer NOT NULL,
+	left integer NOT NULL,
+	odue integer NOT NULL,
+	odid integer NOT NULL,
+	flags integer NOT NULL,
+	data text NOT NULL
+);
+CREATE TABLE revlog (
+	id integer PRIMARY KEY,
+	cid integer NOT NULL,
+	usn integer NOT NULL,
+	ease integer NOT NULL,
+	ivl integer NOT NULL,
+	lastIvl integer NOT NULL,
+	factor integer NOT NULL,
+	time integer NOT NULL,
+	type integer NOT NULL
+);
+CREATE TABLE graves (
+	usn integer NOT NULL,
+	oid integer NOT NULL,
+	type integer NOT NULL
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// WritePackage writes a deck as a package
+func WritePackage(w io.Writer, deck Deck, now time.Time) error {
+	file, err := os.CreateTemp("", "anki-*.db")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %v", err)
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if err := writeCollection(path, deck, now); err != nil {
+		return err
+	}
+
+	collection, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading Anki collection: %v", err)
+	}
+	defer collection.Close()
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: "collection.anki2", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return fmt.Errorf("error writing Anki package: %v", err)
+	}
+	if _, err := io.Copy(entry, collection); err != nil {
+		return fmt.Errorf("error writing Anki package: %v", err)
+	}
+
+	// The package has no media files
+	entry, err = archive.CreateHeader(&zip.FileHeader{Name: "media", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return fmt.Errorf("error writing Anki package: %v", err)
+	}
+	if _, err := io.WriteString(entry, "{}"); err != nil {
+		return fmt.Errorf("error writing Anki package: %v", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing Anki package: %v", err)
+	}
+	return nil
+}
+
+// fieldEscaper turns plain text into the HTML of a note field. Quotes are left
+// alone since Anki shows fields as they are stored when editing them.
+var fieldEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\n", "<br>")
+
+// writeCollection creates the collection of a deck in a new SQLite database
+func writeCollection(path string, deck Deck, now time.Time) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("error creating Anki collection: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return fmt.Errorf("error creating Anki collection: %v", err)
+	}
+
+	// Review cards are due on a day counted from the collection's creation, so
+	// it starts on the day of the earliest due date or review
+	start := now.UTC()
+	for _, note := range deck.Notes {
+		if note.Card.Type == CardReview && note.Card.Due.Before(start) {
+			start = note.Card.Due.UTC()
+		}
+		for _, review := range note.Reviews {
+			if review.Time.Before(start) {
+				start = review.Time.UTC()
+			}
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Anki uses millisecond timestamps as IDs
+	base := now.UnixMilli()
+	noteTypeID, deckID := base, base+1
+	modified := now.Unix()
+
+	conf, models, decks, deckConf, err := collectionConfig(deck, noteTypeID, deckID, modified, len(deck.Notes))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')
+	`, start.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, deckConf)
+	if err != nil {
+		return fmt.Errorf("error writing Anki collection: %v", err)
+	}
+
+	reviewIDs := make(map[int64]bool)
+	for i, note := range deck.Notes {
+		id := base + int64(i)
+		fields := make([]string, len(note.Fields))
+		for j, field := range note.Fields {
+			fields[j] = fieldEscaper.Replace(field)
+		}
+		sortField := ""
+		if len(fields) > 0 {
+			sortField = fields[0]
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+			VALUES (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')
+		`, id, note.GUID, noteTypeID, modified, strings.Join(fields, "\x1f"), sortField, checksum(sortField))
+		if err != nil {
+			return fmt.Errorf("error writing Anki note: %v", err)
+		}
+
+		card := note.Card
+		queue, due, left := CardNew, int64(i+1), 0
+		switch card.Type {
+		case CardReview:
+			queue, due = CardReview, int64(card.Due.Sub(start).Hours()/24)
+		case CardLearning, CardRelearning:
+			queue, due, left = CardLearning, card.Due.Unix(), 1001
+		}
+		if card.Factor == 0 && card.Type != CardNew {
+			card.Factor = 2500
+		}
+		_, err = tx.Exec(`
+			INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+			VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, '')
+		`, id, id, deckID, modified, card.Type, queue, due, card.Interval, card.Factor, card.Reps, card.Lapses, left)
+		if err != nil {
+			return fmt.Errorf("error writing Anki card: %v", err)
+		}
+
+		for _, review := range note.Reviews {
+			// Review IDs are the time of the answer and must be unique
+			reviewID := review.Time.UnixMilli()
+			for reviewIDs[reviewID] {
+				reviewID++
+			}
+			reviewIDs[reviewID] = true
+
+			reviewType := 1
+			if review.Learning {
+				reviewType = 0
+			}
+			_, err := tx.Exec(`
+				INSERT INTO revlog (id, cid, usn, ease, ivl, lastIvl, factor, time, type)
+				VALUES (?, ?, -1, ?, ?, ?, ?, ?, ?)
+			`, reviewID, id, review.Ease, review.Interval, review.LastInterval, review.Factor,
+				min(review.Duration.Milliseconds(), 60000), reviewType)
+			if err != nil {
+				return fmt.Errorf("error writing Anki review: %v", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error writing Anki collection: %v", err)
+	}
+	return nil
+}
+
+// checksum is the note checksum Anki uses to find duplicates: the first 8 hex
+// digits of the SHA-1 of the note's first field
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(PlainText(field)))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+// collectionConfig returns the JSON configuration, note types, decks and deck
+// options of a collection holding a single deck and note type
+func collectionConfig(deck Deck, noteTypeID, deckID, modified int64, notes int) (conf, models, decks, deckConf string, err error) {
+	fields := make([]map[string]any, len(deck.FieldNames))
+	for i, name := range deck.FieldNames {
+		fields[i] = map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+
+	var back strings.Builder
+	back.WriteString("{{FrontSide}}\n\n<hr id=answer>\n\n")
+	for i, name := range deck.BackFields {
+		if i > 0 {
+			back.WriteString("<br>\n")
+		}
+		fmt.Fprintf(&back, "{{%s}}", name)
+	}
+
+	sections := []any{
+		map[string]any{
+			"nextPos": notes + 1, "estTimes": true, "activeDecks": []int64{1}, "sortType": "noteFld",
+			"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": 1, "newBury": true,
+			"newSpread": 0, "dueCounts": true, "curModel": strconv.FormatInt(noteTypeID, 10), "collapseTime": 1200,
+		},
+		map[string]any{
+			strconv.FormatInt(noteTypeID, 10): map[string]any{
+				"id": noteTypeID, "name": deck.NoteType, "type": 0, "mod": modified, "usn": -1,
+				"sortf": 0, "did": deckID, "flds": fields,
+				"tmpls": []map[string]any{{
+					"name": "Card 1", "ord": 0, "qfmt": fmt.Sprintf("{{%s}}", deck.FieldNames[0]),
+					"afmt": back.String(), "bqfmt": "", "bafmt": "", "did": nil, "bfont": "", "bsize": 0,
+				}},
+				"css":       ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n color: black;\n background-color: white;\n}\n",
+				"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+				"latexPost": "\\end{document}",
+				"latexsvg":  false,
+				"req":       []any{[]any{0, "any", []int{0}}},
+				"tags":      []string{},
+				"vers":      []any{},
+			},
+		},
+		map[string]any{
+			"1":                           deckJSON(1, "Default", modified),
+			strconv.FormatInt(deckID, 10): deckJSON(deckID, deck.Name, modified),
+		},
+		map[string]any{
+			"1": map[string]any{
+				"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+				"timer": 0, "replayq": true, "dyn": false,
+				"new": map[string]any{
+					"delays": []float64{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+					"order": 1, "perDay": 20, "bury": true, "separate": true,
+				},
+				"rev": map[string]any{
+					"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500,
+					"bury": true, "minSpace": 1,
+				},
+				"lapse": map[string]any{
+					"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+				},
+			},
+		},
+	}
+
+	encoded := make([]string, len(sections))
+	for i, section := range sections {
+		data, err := json.Marshal(section)
+		if err != nil {
+			return "", "", "", "", fmt.Errorf("error encoding Anki collection: %v", err)
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+// deckJSON describes a deck in the collection's deck list
+func deckJSON(id int64, name string, modified int64) map[string]any {
+	return map[string]any{
+		"id": id, "name": name, "desc": "", "mod": modified, "usn": -1, "dyn": 0, "conf": 1,
+		"collapsed": false, "browserCollapsed": false, "extendNew": 10, "extendRev": 50,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+	}
+}
@@ -0,0 +1,39 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadPackageRejectsLargeCollections(t *testing.T) {
+	// A tiny package whose collection claims to inflate past the limit
+	var compressed bytes.Buffer
+	w, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	w.Write(make([]byte, 1<<20))
+	w.Close()
+
+	var pkg bytes.Buffer
+	archive := zip.NewWriter(&pkg)
+	file, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               "collection.anki2",
+		Method:             zip.Deflate,
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: MaxCollectionSize + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(compressed.Bytes())
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadPackage(bytes.NewReader(pkg.Bytes()), int64(pkg.Len()))
+	if !errors.Is(err, ErrInvalidPackage) || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("got error %v, want the collection rejected as too large", err)
+	}
+}
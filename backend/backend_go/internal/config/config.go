@@ -0,0 +1,75 @@
+// Package config reads the server and task runner settings from the environment.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"pengyou-chinese/backend/internal/service"
+)
+
+const (
+	// DefaultDatabaseURL is the SQLite database used when DATABASE_URL is not set
+	DefaultDatabaseURL = "words.db"
+	// DefaultBackupDir is the directory snapshots are written to when BACKUP_DIR is not set
+	DefaultBackupDir = "backups"
+	// DefaultBackupKeep is the number of snapshots kept when BACKUP_KEEP is not set
+	DefaultBackupKeep = 7
+)
+
+// Config holds the settings shared by the server and the task runner
+type Config struct {
+	// DatabaseURL selects the storage backend: a SQLite file path, a
+	// postgres:// URL, or memory: for a throwaway in-memory store
+	DatabaseURL string
+	// BackupDir is the directory SQLite snapshots are written to
+	BackupDir string
+	// BackupInterval is the time between scheduled snapshots, 0 to take
+	// snapshots only on request
+	BackupInterval time.Duration
+	// BackupKeep is the number of most recent snapshots kept, 0 to keep all
+	BackupKeep int
+	// StaleSessionTimeout is how long an active study session may go without
+	// a review before the server marks it as abandoned
+	StaleSessionTimeout time.Duration
+}
+
+// Load reads the settings from the environment, falling back to the defaults
+func Load() (Config, error) {
+	cfg := Config{
+		DatabaseURL:         DefaultDatabaseURL,
+		BackupDir:           DefaultBackupDir,
+		BackupKeep:          DefaultBackupKeep,
+		StaleSessionTimeout: service.StaleSessionTimeout,
+	}
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		cfg.DatabaseURL = url
+	}
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		cfg.BackupDir = dir
+	}
+	if interval := os.Getenv("BACKUP_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid BACKUP_INTERVAL %q: must be a duration such as 6h or 30m", interval)
+		}
+		cfg.BackupInterval = d
+	}
+	if keep := os.Getenv("BACKUP_KEEP"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("invalid BACKUP_KEEP %q: must be a number of snapshots, 0 to keep all", keep)
+		}
+		cfg.BackupKeep = n
+	}
+	if timeout := os.Getenv("STALE_SESSION_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d < service.StaleSessionTimeout {
+			return Config{}, fmt.Errorf("invalid STALE_SESSION_TIMEOUT %q: must be a duration of at least %.0fh", timeout, service.StaleSessionTimeout.Hours())
+		}
+		cfg.StaleSessionTimeout = d
+	}
+	return cfg, nil
+}
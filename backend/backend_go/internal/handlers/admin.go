@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles routes that reset or maintain the database
+type AdminHandler struct {
+	db        service.Store
+	snapshots service.Snapshots
+}
+
+// NewAdminHandler creates a new admin handler that writes database snapshots
+// as configured by snapshots
+func NewAdminHandler(db service.Store, snapshots service.Snapshots) *AdminHandler {
+	return &AdminHandler{db: db, snapshots: snapshots}
+}
+
+// ResetHistory deletes all study sessions and review items
+func (h *AdminHandler) ResetHistory(c *gin.Context) {
+	var request validation.ResetHistoryRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	if err := h.db.ResetHistory(); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Study history has been reset",
+	})
+}
+
+// FullReset drops all data and re-applies migrations and seeds
+func (h *AdminHandler) FullReset(c *gin.Context) {
+	var request validation.FullResetRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	if err := h.db.FullReset(); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "System has been fully reset",
+	})
+}
+
+// Backup writes a snapshot of the SQLite database while the server keeps
+// serving requests, then removes the oldest snapshots beyond the retention count
+func (h *AdminHandler) Backup(c *gin.Context) {
+	snapshot, err := h.snapshots.Take(h.db)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshot)
+}
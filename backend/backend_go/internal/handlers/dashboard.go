@@ -9,11 +9,11 @@ import (
 
 // DashboardHandler handles dashboard-related routes
 type DashboardHandler struct {
-	db *service.DBService
+	db service.Store
 }
 
 // NewDashboardHandler creates a new dashboard handler
-func NewDashboardHandler(db *service.DBService) *DashboardHandler {
+func NewDashboardHandler(db service.Store) *DashboardHandler {
 	return &DashboardHandler{db: db}
 }
 
@@ -21,12 +21,12 @@ func NewDashboardHandler(db *service.DBService) *DashboardHandler {
 func (h *DashboardHandler) GetLastStudySession(c *gin.Context) {
 	session, err := h.db.GetLastStudySession()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	if session == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No study sessions found"})
+		_ = c.Error(service.ErrNoStudySessions)
 		return
 	}
 
@@ -37,7 +37,7 @@ func (h *DashboardHandler) GetLastStudySession(c *gin.Context) {
 func (h *DashboardHandler) GetStudyProgress(c *gin.Context) {
 	progress, err := h.db.GetStudyProgress()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -48,7 +48,7 @@ func (h *DashboardHandler) GetStudyProgress(c *gin.Context) {
 func (h *DashboardHandler) GetQuickStats(c *gin.Context) {
 	stats, err := h.db.GetQuickStats()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -2,61 +2,54 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GroupsHandler handles group-related routes
 type GroupsHandler struct {
-	db *service.DBService
+	db service.Store
 }
 
 // NewGroupsHandler creates a new groups handler
-func NewGroupsHandler(db *service.DBService) *GroupsHandler {
+func NewGroupsHandler(db service.Store) *GroupsHandler {
 	return &GroupsHandler{db: db}
 }
 
 // GetGroups returns a paginated list of groups
 func (h *GroupsHandler) GetGroups(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
+	options, ok := bindListOptions(c, validation.GroupListFields)
+	if !ok {
+		return
+	}
 
-	groups, total, err := h.db.GetGroups(page, pageSize)
+	groups, info, err := h.db.GetGroups(options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": groups,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
-	})
+	respondList(c, groups, info)
 }
 
 // GetGroup returns a single group by ID
 func (h *GroupsHandler) GetGroup(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
 	group, err := h.db.GetGroup(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	if group == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		_ = c.Error(service.ErrGroupNotFound)
 		return
 	}
 
@@ -65,28 +58,156 @@ func (h *GroupsHandler) GetGroup(c *gin.Context) {
 
 // GetGroupWords returns words for a specific group
 func (h *GroupsHandler) GetGroupWords(c *gin.Context) {
-	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	groupID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	options, ok := bindListOptions(c, validation.WordListFields)
+	if !ok {
+		return
+	}
+
+	words, info, err := h.db.GetGroupWords(groupID, options)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	respondList(c, words, info)
+}
+
+// CreateGroup creates a new group
+func (h *GroupsHandler) CreateGroup(c *gin.Context) {
+	var request validation.GroupRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	group, err := h.db.CreateGroup(request.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// RenameGroup changes the name of a group
+func (h *GroupsHandler) RenameGroup(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.GroupRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	group, err := h.db.RenameGroup(id, request.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if group == nil {
+		_ = c.Error(service.ErrGroupNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup deletes a group and its study sessions, keeping its words
+func (h *GroupsHandler) DeleteGroup(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteGroup(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		_ = c.Error(err)
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
+	if !deleted {
+		_ = c.Error(service.ErrGroupNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"group_id": id,
+	})
+}
+
+// AddGroupWords adds a list of words to a group
+func (h *GroupsHandler) AddGroupWords(c *gin.Context) {
+	groupID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.GroupWordsRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	added, err := h.db.AddWordsToGroup(groupID, request.WordIDs)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"group_id": groupID,
+		"added":    added,
+	})
+}
+
+// RemoveGroupWords removes a list of words from a group
+func (h *GroupsHandler) RemoveGroupWords(c *gin.Context) {
+	groupID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.GroupWordsRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.removeGroupWords(c, groupID, request.WordIDs)
+}
+
+// RemoveGroupWord removes a single word from a group
+func (h *GroupsHandler) RemoveGroupWord(c *gin.Context) {
+	groupID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	wordID, ok := paramID(c, "word_id")
+	if !ok {
+		return
+	}
+
+	h.removeGroupWords(c, groupID, []int64{wordID})
+}
 
-	words, total, err := h.db.GetGroupWords(groupID, page, pageSize)
+func (h *GroupsHandler) removeGroupWords(c *gin.Context, groupID int64, wordIDs []int64) {
+	removed, err := h.db.RemoveWordsFromGroup(groupID, wordIDs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items": words,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
+		"success":  true,
+		"group_id": groupID,
+		"removed":  removed,
 	})
 }
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pengyou-chinese/backend/internal/middleware"
+	"pengyou-chinese/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	middleware.RegisterFieldNames()
+	os.Exit(m.Run())
+}
+
+// newTestAPI serves the API from a memory store holding the seed data
+func newTestAPI(t *testing.T) *gin.Engine {
+	t.Helper()
+	store := service.NewMemoryStore()
+	if err := store.FullReset(); err != nil {
+		t.Fatalf("seeding memory store: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.ErrorHandler())
+	router.NoRoute(middleware.NotFound())
+	RegisterRoutes(router.Group("/api"), store, service.Snapshots{Dir: t.TempDir(), Prefix: "test"})
+	return router
+}
+
+// apiRequest is a request to the test API; Body is sent as JSON unless it is
+// already a reader
+type apiRequest struct {
+	Method      string
+	Path        string
+	Body        any
+	ContentType string
+	Token       string
+}
+
+// serve sends a request to the API and decodes the JSON response into out,
+// returning the status code
+func serve(t *testing.T, router *gin.Engine, req apiRequest, out any) int {
+	t.Helper()
+	var body io.Reader
+	contentType := req.ContentType
+	switch b := req.Body.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	r := httptest.NewRequest(req.Method, req.Path, body)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if req.Token != "" {
+		r.Header.Set("X-Session-Token", req.Token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding response %q: %v", req.Method, req.Path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// expect sends a request and fails the test unless it gets the wanted status
+func expect(t *testing.T, router *gin.Engine, req apiRequest, status int, out any) {
+	t.Helper()
+	var raw json.RawMessage
+	if got := serve(t, router, req, &raw); got != status {
+		t.Fatalf("%s %s: got status %d, want %d: %s", req.Method, req.Path, got, status, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decoding response %s: %v", req.Method, req.Path, raw, err)
+		}
+	}
+}
+
+// expectError sends a request and checks the status and code of its error response
+func expectError(t *testing.T, router *gin.Engine, req apiRequest, status int, code string) middleware.ErrorResponse {
+	t.Helper()
+	var response middleware.ErrorResponse
+	expect(t, router, req, status, &response)
+	if response.Code != code {
+		t.Fatalf("%s %s: got error code %q, want %q: %+v", req.Method, req.Path, response.Code, code, response)
+	}
+	return response
+}
+
+// multipartFile builds a multipart form uploading content as the file field
+// together with the given form fields
+func multipartFile(t *testing.T, name string, content []byte, fields map[string]string) (io.Reader, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for field, value := range fields {
+		if err := writer.WriteField(field, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, writer.FormDataContentType()
+}
+
+type wordItem struct {
+	ID       int64  `json:"id"`
+	Japanese string `json:"japanese"`
+	Romaji   string `json:"romaji"`
+	English  string `json:"english"`
+}
+
+type wordList struct {
+	Items      []wordItem `json:"items"`
+	Pagination struct {
+		CurrentPage  int    `json:"current_page"`
+		ItemsPerPage int    `json:"items_per_page"`
+		TotalItems   int    `json:"total_items"`
+		TotalPages   int    `json:"total_pages"`
+		NextCursor   string `json:"next_cursor"`
+	} `json:"pagination"`
+}
+
+func (l wordList) ids() []int64 {
+	ids := make([]int64, len(l.Items))
+	for i, item := range l.Items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestWords(t *testing.T) {
+	router := newTestAPI(t)
+
+	var created wordItem
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/words", Body: map[string]any{
+		"japanese": "ありがとう", "romaji": "arigatou", "english": "thank you", "group_ids": []int64{1},
+	}}, http.StatusCreated, &created)
+	if created.ID == 0 || created.English != "thank you" {
+		t.Fatalf("got created word %+v", created)
+	}
+
+	response := expectError(t, router, apiRequest{Method: "POST", Path: "/api/words", Body: map[string]any{"japanese": "はい"}},
+		http.StatusBadRequest, middleware.CodeValidation)
+	if len(response.Errors) != 2 || response.Errors[0].Field != "romaji" {
+		t.Fatalf("got validation errors %+v, want romaji and english", response.Errors)
+	}
+	expectError(t, router, apiRequest{Method: "POST", Path: "/api/words", Body: map[string]any{
+		"japanese": "はい", "romaji": "hai", "english": "yes", "group_ids": []int64{99},
+	}}, http.StatusUnprocessableEntity, "invalid_reference")
+
+	var patched wordItem
+	expect(t, router, apiRequest{Method: "PATCH", Path: "/api/words/11", Body: map[string]any{"english": "thanks"}}, http.StatusOK, &patched)
+	if patched.English != "thanks" || patched.Romaji != "arigatou" {
+		t.Fatalf("got patched word %+v, want only english changed", patched)
+	}
+
+	expect(t, router, apiRequest{Method: "DELETE", Path: "/api/words/11"}, http.StatusOK, nil)
+	expectError(t, router, apiRequest{Method: "GET", Path: "/api/words/11"}, http.StatusNotFound, "word_not_found")
+	expectError(t, router, apiRequest{Method: "GET", Path: "/api/nowhere"}, http.StatusNotFound, middleware.CodeRouteNotFound)
+}
+
+func TestWordLists(t *testing.T) {
+	router := newTestAPI(t)
+
+	var list wordList
+	expect(t, router, apiRequest{Method: "GET", Path: "/api/words?page=2&page_size=4"}, http.StatusOK, &list)
+	if len(list.Items) != 4 || list.Pagination.CurrentPage != 2 || list.Pagination.TotalItems != 10 || list.Pagination.TotalPages != 3 {
+		t.Fatalf("got page %+v", list)
+	}
+
+	expect(t, router, apiRequest{Method: "GET", Path: "/api/words?sort_by=english&order=desc&filter=english:contains:good"}, http.StatusOK, &list)
+	for i, item := range list.Items {
+		if i > 0 && item.English > list.Items[i-1].English {
+			t.Fatalf("got words %+v, want them by english descending", list.Items)
+		}
+	}
+	if len(list.Items) != 3 {
+		t.Fatalf("got %d words containing good, want 3", len(list.Items))
+	}
+
+	for _, test := range []struct {
+		query string
+		field string
+	}{
+		{"page=0", "page"},
+		{"page_size=0", "page_size"},
+		{"page_size=101", "page_size"},
+		{"page=abc", "page"},
+		{"sort_by=color", "sort_by"},
+		{"filter=english:like:x", "filter"},
+		{"cursor=nonsense", "cursor"},
+	} {
+		response := expectError(t, router, apiRequest{Method: "GET", Path: "/api/words?" + test.query}, http.StatusBadRequest, middleware.CodeValidation)
+		if len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("%s: got errors %+v, want one for %s", test.query, response.Errors, test.field)
+		}
+	}
+
+	var all wordList
+	expect(t, router, apiRequest{Method: "GET", Path: "/api/words?sort_by=romaji"}, http.StatusOK, &all)
+	var paged []int64
+	cursor := ""
+	for {
+		var page wordList
+		expect(t, router, apiRequest{Method: "GET", Path: "/api/words?sort_by=romaji&page_size=3&cursor=" + cursor}, http.StatusOK, &page)
+		paged = append(paged, page.ids()...)
+		if page.Pagination.NextCursor == "" {
+			break
+		}
+		cursor = page.Pagination.NextCursor
+		if len(paged) > len(all.Items) {
+			t.Fatal("cursor pages go past the last word")
+		}
+	}
+	if want := all.ids(); len(paged) != len(want) {
+		t.Fatalf("cursor pages gave %v, want %v", paged, want)
+	}
+	for i := range paged {
+		if paged[i] != all.Items[i].ID {
+			t.Fatalf("cursor pages gave %v, want %v", paged, all.ids())
+		}
+	}
+
+	expect(t, router, apiRequest{Method: "GET", Path: "/api/words/search?q=hello"}, http.StatusOK, &list)
+	if len(list.Items) != 1 || list.Items[0].Romaji != "konnichiwa" {
+		t.Fatalf("searching hello: got %+v", list.Items)
+	}
+	expectError(t, router, apiRequest{Method: "GET", Path: "/api/words/search"}, http.StatusBadRequest, middleware.CodeValidation)
+}
+
+func TestGroups(t *testing.T) {
+	router := newTestAPI(t)
+
+	var group struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/groups", Body: map[string]string{"name": "Food"}}, http.StatusCreated, &group)
+
+	var changed struct {
+		Added   int `json:"added"`
+		Removed int `json:"removed"`
+	}
+	path := "/api/groups/" + itoa(group.ID) + "/words"
+	expect(t, router, apiRequest{Method: "POST", Path: path, Body: map[string][]int64{"word_ids": {1, 2, 3}}}, http.StatusOK, &changed)
+	if changed.Added != 3 {
+		t.Fatalf("got %d words added, want 3", changed.Added)
+	}
+	expectError(t, router, apiRequest{Method: "POST", Path: path, Body: map[string][]int64{"word_ids": {}}}, http.StatusBadRequest, middleware.CodeValidation)
+	expectError(t, router, apiRequest{Method: "POST", Path: path, Body: map[string][]int64{"word_ids": {999}}}, http.StatusUnprocessableEntity, "invalid_reference")
+	expect(t, router, apiRequest{Method: "DELETE", Path: path + "/2"}, http.StatusOK, &changed)
+	if changed.Removed != 1 {
+		t.Fatalf("got %d words removed, want 1", changed.Removed)
+	}
+
+	var words wordList
+	expect(t, router, apiRequest{Method: "GET", Path: path}, http.StatusOK, &words)
+	if got := words.ids(); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("got group words %v, want [1 3]", got)
+	}
+
+	expect(t, router, apiRequest{Method: "PUT", Path: "/api/groups/" + itoa(group.ID), Body: map[string]string{"name": "Drinks"}}, http.StatusOK, &group)
+	if group.Name != "Drinks" {
+		t.Fatalf("got group %+v after renaming", group)
+	}
+	expect(t, router, apiRequest{Method: "DELETE", Path: "/api/groups/" + itoa(group.ID)}, http.StatusOK, nil)
+	expectError(t, router, apiRequest{Method: "GET", Path: "/api/groups/" + itoa(group.ID)}, http.StatusNotFound, "group_not_found")
+}
+
+type batchResult struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Results   []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	} `json:"results"`
+}
+
+func TestStudySessions(t *testing.T) {
+	router := newTestAPI(t)
+
+	var launch struct {
+		StudySession struct {
+			ID int64 `json:"id"`
+		} `json:"study_session"`
+		Token     string `json:"token"`
+		LaunchURL string `json:"launch_url"`
+	}
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/study_activities/1/launch", Body: map[string]int64{"group_id": 1}}, http.StatusCreated, &launch)
+	if strings.Contains(launch.LaunchURL, launch.Token) {
+		t.Fatalf("launch URL %s reveals the session token", launch.LaunchURL)
+	}
+	reviews := "/api/study_sessions/" + itoa(launch.StudySession.ID) + "/reviews"
+	review := map[string]any{"reviews": []map[string]any{{"word_id": 1, "correct": true}}}
+
+	expectError(t, router, apiRequest{Method: "POST", Path: reviews, Body: review}, http.StatusUnauthorized, "session_token_required")
+	expectError(t, router, apiRequest{Method: "POST", Path: reviews + "?token=" + launch.Token, Body: review}, http.StatusUnauthorized, "session_token_required")
+	expectError(t, router, apiRequest{Method: "POST", Path: reviews, Body: review, Token: "wrong"}, http.StatusUnauthorized, "invalid_session_token")
+
+	var batch batchResult
+	expect(t, router, apiRequest{Method: "POST", Path: reviews, Token: launch.Token, Body: map[string]any{"reviews": []map[string]any{
+		{"word_id": 1, "correct": true},
+		{"word_id": 6, "correct": true},
+	}}}, http.StatusOK, &batch)
+	if batch.Succeeded != 1 || batch.Failed != 1 || batch.Results[1].Success {
+		t.Fatalf("got batch result %+v, want the review of word 6 outside the group to fail", batch)
+	}
+
+	for _, answeredAt := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(-time.Hour)} {
+		expectError(t, router, apiRequest{Method: "POST", Path: reviews, Token: launch.Token, Body: map[string]any{"reviews": []map[string]any{
+			{"word_id": 1, "correct": true, "answered_at": answeredAt},
+		}}}, http.StatusUnprocessableEntity, "answered_at_out_of_range")
+	}
+
+	finish := "/api/study_sessions/" + itoa(launch.StudySession.ID) + "/finish"
+	expectError(t, router, apiRequest{Method: "POST", Path: finish}, http.StatusUnauthorized, "session_token_required")
+	expectError(t, router, apiRequest{Method: "POST", Path: finish, Token: "wrong"}, http.StatusUnauthorized, "invalid_session_token")
+	expect(t, router, apiRequest{Method: "POST", Path: finish, Token: launch.Token}, http.StatusOK, nil)
+	expectError(t, router, apiRequest{Method: "POST", Path: finish, Token: launch.Token}, http.StatusConflict, "study_session_not_active")
+	expectError(t, router, apiRequest{Method: "POST", Path: reviews, Body: review, Token: launch.Token}, http.StatusConflict, "study_session_not_active")
+	expectError(t, router, apiRequest{Method: "POST", Path: "/api/study_sessions/" + itoa(launch.StudySession.ID) + "/words/1/review", Body: map[string]bool{"correct": true}, Token: launch.Token},
+		http.StatusConflict, "study_session_not_active")
+
+	var session struct {
+		ID int64 `json:"id"`
+	}
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/study_sessions", Body: map[string]int64{"group_id": 2, "study_activity_id": 1}}, http.StatusCreated, &session)
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/study_sessions/" + itoa(session.ID) + "/words/6/review", Body: map[string]bool{"correct": false}}, http.StatusOK, nil)
+}
+
+func TestAdmin(t *testing.T) {
+	router := newTestAPI(t)
+
+	expectError(t, router, apiRequest{Method: "POST", Path: "/api/reset_history", Body: map[string]string{"confirm": "yes"}}, http.StatusBadRequest, middleware.CodeValidation)
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/reset_history", Body: map[string]string{"confirm": "RESET_HISTORY"}}, http.StatusOK, nil)
+	expectError(t, router, apiRequest{Method: "POST", Path: "/api/full_reset", Body: map[string]string{"confirm": "RESET_HISTORY"}}, http.StatusBadRequest, middleware.CodeValidation)
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/full_reset", Body: map[string]string{"confirm": "FULL_RESET"}}, http.StatusOK, nil)
+	expectError(t, router, apiRequest{Method: "POST", Path: "/api/backup"}, http.StatusConflict, "snapshots_unsupported")
+}
+
+func TestImportAndBackup(t *testing.T) {
+	router := newTestAPI(t)
+
+	csv := "japanese,romaji,english\nねこ,neko,cat\nいぬ,inu,dog\n"
+	body, contentType := multipartFile(t, "animals.csv", []byte(csv), map[string]string{"group_name": "Animals"})
+	var imported struct {
+		Group struct {
+			ID int64 `json:"id"`
+		} `json:"group"`
+		GroupCreated bool `json:"group_created"`
+		Created      int  `json:"created"`
+	}
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/import", Body: body, ContentType: contentType}, http.StatusOK, &imported)
+	if !imported.GroupCreated || imported.Created != 2 {
+		t.Fatalf("got import result %+v, want 2 words in a new group", imported)
+	}
+
+	body, contentType = multipartFile(t, "animals.csv", []byte("japanese,romaji,english\nねこ,neko,\n"), nil)
+	response := expectError(t, router, apiRequest{Method: "POST", Path: "/api/import", Body: body, ContentType: contentType}, http.StatusBadRequest, middleware.CodeValidation)
+	if len(response.Errors) != 1 || response.Errors[0].Line != 2 || response.Errors[0].Field != "english" {
+		t.Fatalf("got import errors %+v, want english missing on line 2", response.Errors)
+	}
+
+	for header, content := range map[string]string{"true": csv, "false": "ねこ,neko,cat\nいぬ,inu,dog\n"} {
+		body, contentType = multipartFile(t, "animals.csv", []byte(content), map[string]string{"header": header, "english_column": "5"})
+		response = expectError(t, router, apiRequest{Method: "POST", Path: "/api/import", Body: body, ContentType: contentType}, http.StatusBadRequest, middleware.CodeValidation)
+		if len(response.Errors) != 1 || response.Errors[0].Field != "english_column" || response.Errors[0].Line != 0 {
+			t.Fatalf("header=%s: got import errors %+v, want one for english_column", header, response.Errors)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("exporting backup: got status %d: %s", w.Code, w.Body)
+	}
+	backup := w.Body.Bytes()
+
+	restored := newTestAPI(t)
+	expect(t, restored, apiRequest{Method: "POST", Path: "/api/full_reset", Body: map[string]string{"confirm": "FULL_RESET"}}, http.StatusOK, nil)
+	body, contentType = multipartFile(t, "backup.json", backup, nil)
+	var result struct {
+		Words struct {
+			Created  int `json:"created"`
+			Existing int `json:"existing"`
+		} `json:"words"`
+	}
+	expect(t, restored, apiRequest{Method: "POST", Path: "/api/import/backup", Body: body, ContentType: contentType}, http.StatusOK, &result)
+	if result.Words.Created != 2 || result.Words.Existing != 10 {
+		t.Fatalf("got restored words %+v, want the 2 imported words created", result.Words)
+	}
+
+	var words wordList
+	expect(t, restored, apiRequest{Method: "GET", Path: "/api/groups/" + itoa(imported.Group.ID) + "/words"}, http.StatusOK, &words)
+	if len(words.Items) != 2 {
+		t.Fatalf("got %d words in the restored group, want 2", len(words.Items))
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	router := gin.New()
+	router.Use(middleware.Logger())
+	router.Use(middleware.ErrorHandler())
+	store := service.NewMemoryStore()
+	if err := store.FullReset(); err != nil {
+		t.Fatalf("seeding memory store: %v", err)
+	}
+	RegisterRoutes(router.Group("/api"), store, service.Snapshots{Dir: t.TempDir(), Prefix: "test"})
+
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/words", Body: map[string]any{"japanese": "ねこ", "romaji": "neko", "english": "cat"}}, http.StatusCreated, nil)
+	if !strings.Contains(logged.String(), `"romaji":"neko"`) {
+		t.Fatalf("JSON request missing from the log:\n%s", logged.String())
+	}
+
+	// Uploads and downloads stay out of the log, and large JSON bodies still
+	// reach the handler in full
+	body, contentType := multipartFile(t, "animals.csv", []byte("japanese,romaji,english\nいぬ,inu,dog\n"), map[string]string{"group_name": "Animals"})
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/import", Body: body, ContentType: contentType}, http.StatusOK, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("exporting backup: got status %d: %s", w.Code, w.Body)
+	}
+	english := strings.Repeat("wolf ", 2000)
+	expect(t, router, apiRequest{Method: "POST", Path: "/api/words", Body: map[string]any{"japanese": "おおかみ", "romaji": "ookami", "english": english}}, http.StatusCreated, nil)
+	if strings.Contains(logged.String(), "inu") || strings.Contains(logged.String(), "wolf") {
+		t.Fatalf("upload, download or large body written to the log:\n%s", logged.String())
+	}
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
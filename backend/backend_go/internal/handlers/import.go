@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/anki"
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles vocabulary file uploads, Anki deck exports and backups
+type ImportHandler struct {
+	db service.Store
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(db service.Store) *ImportHandler {
+	return &ImportHandler{db: db}
+}
+
+// ImportWords imports an uploaded CSV, TSV or JSON vocabulary file into an
+// existing group, or the group named group_name which is created if needed.
+// Nothing is imported unless every row is valid.
+func (h *ImportHandler) ImportWords(c *gin.Context) {
+	// Leave room for the form fields next to the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImportSize+1<<20)
+
+	var request validation.ImportRequest
+	if !bindForm(c, &request) {
+		return
+	}
+	if request.GroupID > 0 && request.GroupName != "" {
+		_ = c.Error(&validation.FieldError{Field: "group_name", Message: "Cannot be combined with group_id"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "This field is required"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if header.Size > service.MaxImportSize {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "Must be at most 10 MB"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	format := request.Format
+	if format == "" {
+		format = service.ImportFormatOf(header.Filename)
+	}
+	if format == "" {
+		_ = c.Error(&validation.FieldError{Field: "format", Message: "Must be one of: csv tsv json, or given by the file extension"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	columns := service.ImportColumns{
+		Japanese: request.JapaneseColumn,
+		Romaji:   request.RomajiColumn,
+		English:  request.EnglishColumn,
+		Parts:    request.PartsColumn,
+	}
+	parsed, err := service.ParseImport(file, format, request.Header == nil || *request.Header, columns)
+	if err != nil {
+		var fieldErr *validation.FieldError
+		var rowErrs validation.RowErrors
+		if errors.As(err, &fieldErr) || errors.As(err, &rowErrs) {
+			_ = c.Error(err).SetType(gin.ErrorTypeBind)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	groupName := request.GroupName
+	if groupName == "" {
+		groupName = parsed.GroupName
+	}
+	if request.GroupID == 0 && groupName == "" {
+		_ = c.Error(&validation.FieldError{Field: "group_name", Message: "This field is required unless group_id is given"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	results, err := h.db.ImportWords([]models.GroupImport{{
+		GroupID:   request.GroupID,
+		GroupName: groupName,
+		Words:     parsed.Words,
+	}})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results[0])
+}
+
+// ImportAnki imports the notes of an uploaded Anki package (.apkg) along with
+// the review schedules of their cards. Each deck goes into the group named
+// after it unless group_id is given.
+func (h *ImportHandler) ImportAnki(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAnkiImportSize+1<<20)
+
+	var request validation.AnkiImportRequest
+	if !bindForm(c, &request) {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "This field is required"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if header.Size > service.MaxAnkiImportSize {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "Must be at most 100 MB"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	notes, err := anki.ReadPackage(file, header.Size)
+	if err != nil {
+		if errors.Is(err, anki.ErrInvalidPackage) {
+			_ = c.Error(&validation.FieldError{Field: "file", Message: "Must be an Anki package: " + strings.TrimPrefix(err.Error(), anki.ErrInvalidPackage.Error()+": ")}).SetType(gin.ErrorTypeBind)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	fields := service.AnkiFields{
+		Japanese: request.JapaneseField,
+		Romaji:   request.RomajiField,
+		English:  request.EnglishField,
+		Parts:    request.PartsField,
+		Reading:  request.ReadingField,
+	}
+	imports, skipped, err := service.AnkiImports(notes, fields, request.GroupID, request.SkipInvalid)
+	if err != nil {
+		var fieldErr *validation.FieldError
+		var rowErrs validation.RowErrors
+		if errors.As(err, &fieldErr) || errors.As(err, &rowErrs) {
+			_ = c.Error(err).SetType(gin.ErrorTypeBind)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	results, err := h.db.ImportWords(imports)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":  results,
+		"skipped": skipped,
+	})
+}
+
+// ExportAnki downloads a group as an Anki package with the review schedules
+// and history of its words
+func (h *ImportHandler) ExportAnki(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.db.ExportGroup(id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if export == nil {
+		_ = c.Error(service.ErrGroupNotFound)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := anki.WritePackage(&buf, service.AnkiDeck(export), time.Now()); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Group.Name + ".apkg"}))
+	c.Data(http.StatusOK, "application/apkg", buf.Bytes())
+}
+
+// ExportBackup downloads a versioned archive of all words, groups, study
+// activities and study history
+func (h *ImportHandler) ExportBackup(c *gin.Context) {
+	backup, err := h.db.ExportBackup()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	filename := "pengyou-backup-" + backup.ExportedAt.Format("20060102-150405") + ".json"
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.JSON(http.StatusOK, backup)
+}
+
+// RestoreBackup restores an uploaded backup archive alongside the existing
+// data. Nothing is restored unless every record of the archive is valid.
+func (h *ImportHandler) RestoreBackup(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxBackupSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "This field is required"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if header.Size > service.MaxBackupSize {
+		_ = c.Error(&validation.FieldError{Field: "file", Message: "Must be at most 100 MB"}).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	backup, err := service.ParseBackup(file)
+	if err != nil {
+		var fieldErr *validation.FieldError
+		var rowErrs validation.RowErrors
+		if errors.As(err, &fieldErr) || errors.As(err, &rowErrs) {
+			_ = c.Error(err).SetType(gin.ErrorTypeBind)
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	result, err := h.db.RestoreBackup(backup)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
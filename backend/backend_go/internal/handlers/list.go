@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paramID parses a positive integer ID from the named path parameter, reporting
+// bad input like bindQuery
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		_ = c.Error(&validation.FieldError{Field: name, Message: "Must be a positive integer"}).SetType(gin.ErrorTypeBind)
+		return 0, false
+	}
+	return id, true
+}
+
+// bindQuery binds query parameters into obj. On bad input the error is passed
+// to the error middleware, which responds with the failing fields.
+func bindQuery(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		_ = c.Error(validation.ParamError(obj, c.Request.URL.Query(), err)).SetType(gin.ErrorTypeBind)
+		return false
+	}
+	return true
+}
+
+// bindJSON binds the JSON request body into obj, reporting bad input like bindQuery
+func bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return false
+	}
+	return true
+}
+
+// bindForm binds the form fields of a urlencoded or multipart request body
+// into obj, reporting bad input like bindQuery
+func bindForm(c *gin.Context, obj any) bool {
+	if err := c.ShouldBind(obj); err != nil {
+		_ = c.Error(validation.ParamError(obj, c.Request.Form, err)).SetType(gin.ErrorTypeBind)
+		return false
+	}
+	return true
+}
+
+// bindListOptions binds the pagination, sort, filter and cursor query parameters
+// and checks them against the given whitelist. It returns false on bad input.
+func bindListOptions(c *gin.Context, fields validation.ListFields) (validation.ListOptions, bool) {
+	var request validation.ListRequest
+	if !bindQuery(c, &request) {
+		return validation.ListOptions{}, false
+	}
+
+	options, err := validation.ParseListOptions(request, fields)
+	if err != nil {
+		_ = c.Error(err).SetType(gin.ErrorTypeBind)
+		return validation.ListOptions{}, false
+	}
+
+	return options, true
+}
+
+// respondList writes one page of a list endpoint
+func respondList(c *gin.Context, items any, info models.PageInfo) {
+	c.JSON(http.StatusOK, gin.H{
+		"items":      items,
+		"pagination": paginationResponse(info),
+	})
+}
+
+// paginationResponse renders the pagination block of a list response. Cursor
+// mode reports next_cursor, null on the last page, instead of page totals.
+func paginationResponse(info models.PageInfo) gin.H {
+	if info.CursorMode {
+		var nextCursor any
+		if info.NextCursor != "" {
+			nextCursor = info.NextCursor
+		}
+		return gin.H{
+			"items_per_page": info.PageSize,
+			"next_cursor":    nextCursor,
+		}
+	}
+
+	return gin.H{
+		"current_page":   info.Page,
+		"total_pages":    (info.TotalItems + info.PageSize - 1) / info.PageSize,
+		"total_items":    info.TotalItems,
+		"items_per_page": info.PageSize,
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewsHandler handles spaced-repetition review routes
+type ReviewsHandler struct {
+	db service.Store
+}
+
+// NewReviewsHandler creates a new reviews handler
+func NewReviewsHandler(db service.Store) *ReviewsHandler {
+	return &ReviewsHandler{db: db}
+}
+
+// GetDueWords returns a paginated list of words that are due for review
+func (h *ReviewsHandler) GetDueWords(c *gin.Context) {
+	var request validation.DueReviewsRequest
+	if !bindQuery(c, &request) {
+		return
+	}
+
+	options, ok := bindListOptions(c, validation.DueWordListFields)
+	if !ok {
+		return
+	}
+
+	words, info, err := h.db.GetDueWords(request.GroupID, options)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	respondList(c, words, info)
+}
+
+// AddReviews records a batch of reviews for a study session in one transaction
+func (h *ReviewsHandler) AddReviews(c *gin.Context) {
+	sessionID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorizeSessionToken(c, h.db, sessionID) {
+		return
+	}
+
+	var request validation.BatchReviewRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	now := time.Now().UTC()
+	reviews := make([]models.WordReviewItem, len(request.Reviews))
+	for i, item := range request.Reviews {
+		answeredAt := now
+		if item.AnsweredAt != nil {
+			answeredAt = item.AnsweredAt.UTC()
+		}
+		reviews[i] = reviewItem(item.AddWordReviewRequest)
+		reviews[i].WordID = item.WordID
+		reviews[i].CreatedAt = answeredAt
+	}
+
+	results, err := h.db.AddWordReviews(sessionID, reviews)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"study_session_id": sessionID,
+		"succeeded":        succeeded,
+		"failed":           len(results) - succeeded,
+		"results":          results,
+	})
+}
+
+// reviewItem converts a validated review payload into a review record
+func reviewItem(request validation.AddWordReviewRequest) models.WordReviewItem {
+	return models.WordReviewItem{
+		Correct:        *request.Correct,
+		Answer:         request.Answer,
+		ExpectedAnswer: request.ExpectedAnswer,
+		ResponseMS:     request.ResponseMS,
+		HintUsed:       request.HintUsed,
+		Grade:          request.Grade,
+	}
+}
+
+// sessionToken returns the study session token sent in the X-Session-Token
+// header. Tokens are never read from the URL, where they would end up in logs
+// and browser history.
+func sessionToken(c *gin.Context) string {
+	return c.GetHeader("X-Session-Token")
+}
+
+// authorizeSessionToken checks the session token of a launched study app.
+// Sessions started through the launch endpoint require their token; other
+// sessions accept requests without one. It writes the error response and
+// returns false when the token is missing, wrong or expired.
+func authorizeSessionToken(c *gin.Context, db service.Store, sessionID int64) bool {
+	if err := db.ValidateSessionToken(sessionID, sessionToken(c)); err != nil {
+		_ = c.Error(err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"pengyou-chinese/backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the API routes on api, served from the given store.
+// Admin backups write snapshots as configured by snapshots.
+func RegisterRoutes(api *gin.RouterGroup, db service.Store, snapshots service.Snapshots) {
+	// Initialize handlers
+	dashboardHandler := NewDashboardHandler(db)
+	wordsHandler := NewWordsHandler(db)
+	groupsHandler := NewGroupsHandler(db)
+	studyHandler := NewStudyHandler(db)
+	reviewsHandler := NewReviewsHandler(db)
+	adminHandler := NewAdminHandler(db, snapshots)
+	importHandler := NewImportHandler(db)
+
+	// Dashboard routes
+	api.GET("/dashboard/last_study_session", dashboardHandler.GetLastStudySession)
+	api.GET("/dashboard/study_progress", dashboardHandler.GetStudyProgress)
+	api.GET("/dashboard/quick-stats", dashboardHandler.GetQuickStats)
+
+	// Words routes
+	api.GET("/words", wordsHandler.GetWords)
+	api.GET("/words/search", wordsHandler.SearchWords)
+	api.GET("/words/:id", wordsHandler.GetWord)
+	api.GET("/words/:id/reviews", wordsHandler.GetWordReviews)
+	api.POST("/words", wordsHandler.CreateWord)
+	api.PUT("/words/:id", wordsHandler.UpdateWord)
+	api.PATCH("/words/:id", wordsHandler.PatchWord)
+	api.DELETE("/words/:id", wordsHandler.DeleteWord)
+	api.POST("/study_sessions/:id/words/:word_id/review", wordsHandler.AddWordReview)
+
+	// Reviews routes
+	api.GET("/reviews/due", reviewsHandler.GetDueWords)
+	api.POST("/study_sessions/:id/reviews", reviewsHandler.AddReviews)
+
+	// Groups routes
+	api.GET("/groups", groupsHandler.GetGroups)
+	api.GET("/groups/:id", groupsHandler.GetGroup)
+	api.GET("/groups/:id/words", groupsHandler.GetGroupWords)
+	api.POST("/groups", groupsHandler.CreateGroup)
+	api.PUT("/groups/:id", groupsHandler.RenameGroup)
+	api.DELETE("/groups/:id", groupsHandler.DeleteGroup)
+	api.POST("/groups/:id/words", groupsHandler.AddGroupWords)
+	api.DELETE("/groups/:id/words", groupsHandler.RemoveGroupWords)
+	api.DELETE("/groups/:id/words/:word_id", groupsHandler.RemoveGroupWord)
+
+	// Study sessions routes
+	api.GET("/study_sessions", studyHandler.GetStudySessions)
+	api.GET("/study_sessions/:id", studyHandler.GetStudySession)
+	api.GET("/study_sessions/:id/words", studyHandler.GetStudySessionWords)
+	api.POST("/study_sessions", studyHandler.CreateStudySession)
+	api.POST("/study_sessions/:id/finish", studyHandler.FinishStudySession)
+
+	// Study activities routes
+	api.GET("/study_activities", studyHandler.GetStudyActivities)
+	api.GET("/study_activities/:id", studyHandler.GetStudyActivity)
+	api.POST("/study_activities", studyHandler.CreateStudyActivity)
+	api.PUT("/study_activities/:id", studyHandler.UpdateStudyActivity)
+	api.PATCH("/study_activities/:id", studyHandler.PatchStudyActivity)
+	api.DELETE("/study_activities/:id", studyHandler.DeleteStudyActivity)
+	api.POST("/study_activities/:id/launch", studyHandler.LaunchStudyActivity)
+	api.GET("/study_activities/:id/study_sessions", studyHandler.GetStudyActivitySessions)
+
+	// Admin routes
+	api.POST("/reset_history", adminHandler.ResetHistory)
+	api.POST("/full_reset", adminHandler.FullReset)
+	api.POST("/backup", adminHandler.Backup)
+
+	// Import routes
+	api.POST("/import", importHandler.ImportWords)
+	api.POST("/import/anki", importHandler.ImportAnki)
+	api.GET("/groups/:id/anki", importHandler.ExportAnki)
+	api.POST("/import/backup", importHandler.RestoreBackup)
+	api.GET("/export", importHandler.ExportBackup)
+}
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
+	"pengyou-chinese/backend/internal/models"
 	"pengyou-chinese/backend/internal/service"
 	"pengyou-chinese/backend/internal/validation"
 
@@ -12,45 +12,34 @@ import (
 
 // StudyHandler handles study session and activity related routes
 type StudyHandler struct {
-	db *service.DBService
+	db service.Store
 }
 
 // NewStudyHandler creates a new study handler
-func NewStudyHandler(db *service.DBService) *StudyHandler {
+func NewStudyHandler(db service.Store) *StudyHandler {
 	return &StudyHandler{db: db}
 }
 
 // GetStudySessions returns a paginated list of study sessions
 func (h *StudyHandler) GetStudySessions(c *gin.Context) {
-	var pagination validation.PaginationRequest
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+	options, ok := bindListOptions(c, validation.StudySessionListFields)
+	if !ok {
 		return
 	}
 
-	page, pageSize := validation.GetDefaultPagination(pagination.Page, pagination.PageSize)
-	sessions, total, err := h.db.GetStudySessions(page, pageSize)
+	sessions, info, err := h.db.GetStudySessions(options)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": sessions,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
-	})
+	respondList(c, sessions, info)
 }
 
 // GetStudySession returns a single study session by ID
 func (h *StudyHandler) GetStudySession(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
@@ -61,7 +50,7 @@ func (h *StudyHandler) GetStudySession(c *gin.Context) {
 	}
 
 	if session == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Study session not found"})
+		_ = c.Error(service.ErrSessionNotFound)
 		return
 	}
 
@@ -70,41 +59,50 @@ func (h *StudyHandler) GetStudySession(c *gin.Context) {
 
 // GetStudySessionWords returns words reviewed in a study session
 func (h *StudyHandler) GetStudySessionWords(c *gin.Context) {
-	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	sessionID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	options, ok := bindListOptions(c, validation.WordListFields)
+	if !ok {
+		return
+	}
+
+	words, info, err := h.db.GetStudySessionWords(sessionID, options)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		_ = c.Error(err)
+		return
+	}
+
+	respondList(c, words, info)
+}
+
+// GetStudyActivities returns a paginated list of study activities
+func (h *StudyHandler) GetStudyActivities(c *gin.Context) {
+	var request validation.StudyActivitiesRequest
+	if !bindQuery(c, &request) {
 		return
 	}
 
-	var pagination validation.PaginationRequest
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+	options, ok := bindListOptions(c, validation.StudyActivityListFields)
+	if !ok {
 		return
 	}
 
-	page, pageSize := validation.GetDefaultPagination(pagination.Page, pagination.PageSize)
-	words, total, err := h.db.GetStudySessionWords(sessionID, page, pageSize)
+	activities, info, err := h.db.GetStudyActivities(request.EnabledOnly, options)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": words,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
-	})
+	respondList(c, activities, info)
 }
 
 // GetStudyActivity returns a study activity by ID
 func (h *StudyHandler) GetStudyActivity(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity ID"})
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
@@ -115,7 +113,7 @@ func (h *StudyHandler) GetStudyActivity(c *gin.Context) {
 	}
 
 	if activity == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Study activity not found"})
+		_ = c.Error(service.ErrActivityNotFound)
 		return
 	}
 
@@ -124,41 +122,29 @@ func (h *StudyHandler) GetStudyActivity(c *gin.Context) {
 
 // GetStudyActivitySessions returns study sessions for a specific activity
 func (h *StudyHandler) GetStudyActivitySessions(c *gin.Context) {
-	activityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity ID"})
+	activityID, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	var pagination validation.PaginationRequest
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+	options, ok := bindListOptions(c, validation.StudySessionListFields)
+	if !ok {
 		return
 	}
 
-	page, pageSize := validation.GetDefaultPagination(pagination.Page, pagination.PageSize)
-	sessions, total, err := h.db.GetStudyActivitySessions(activityID, page, pageSize)
+	sessions, info, err := h.db.GetStudyActivitySessions(activityID, options)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": sessions,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
-	})
+	respondList(c, sessions, info)
 }
 
 // CreateStudySession creates a new study session
 func (h *StudyHandler) CreateStudySession(c *gin.Context) {
 	var request validation.CreateStudySessionRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if !bindJSON(c, &request) {
 		return
 	}
 
@@ -170,3 +156,159 @@ func (h *StudyHandler) CreateStudySession(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, session)
 }
+
+// CreateStudyActivity adds a new activity to the catalog
+func (h *StudyHandler) CreateStudyActivity(c *gin.Context) {
+	var request validation.StudyActivityRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	activity, err := h.db.CreateStudyActivity(models.StudyActivity{
+		Name:         request.Name,
+		Description:  request.Description,
+		ThumbnailURL: request.ThumbnailURL,
+		LaunchURL:    request.LaunchURL,
+		Enabled:      request.Enabled == nil || *request.Enabled,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, activity)
+}
+
+// UpdateStudyActivity replaces a study activity's fields
+func (h *StudyHandler) UpdateStudyActivity(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.StudyActivityRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	enabled := request.Enabled == nil || *request.Enabled
+	h.updateStudyActivity(c, id, models.StudyActivityUpdate{
+		Name:         &request.Name,
+		Description:  &request.Description,
+		ThumbnailURL: &request.ThumbnailURL,
+		LaunchURL:    &request.LaunchURL,
+		Enabled:      &enabled,
+	})
+}
+
+// PatchStudyActivity updates only the fields present in the request
+func (h *StudyHandler) PatchStudyActivity(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.PatchStudyActivityRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.updateStudyActivity(c, id, models.StudyActivityUpdate{
+		Name:         request.Name,
+		Description:  request.Description,
+		ThumbnailURL: request.ThumbnailURL,
+		LaunchURL:    request.LaunchURL,
+		Enabled:      request.Enabled,
+	})
+}
+
+func (h *StudyHandler) updateStudyActivity(c *gin.Context, id int64, update models.StudyActivityUpdate) {
+	activity, err := h.db.UpdateStudyActivity(id, update)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if activity == nil {
+		_ = c.Error(service.ErrActivityNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
+
+// DeleteStudyActivity removes an activity that has no study sessions from the catalog
+func (h *StudyHandler) DeleteStudyActivity(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteStudyActivity(id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if !deleted {
+		_ = c.Error(service.ErrActivityNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"study_activity_id": id,
+	})
+}
+
+// LaunchStudyActivity starts a study session for a group and returns the URL
+// that opens the external study app for that session
+func (h *StudyHandler) LaunchStudyActivity(c *gin.Context) {
+	activityID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.LaunchStudyActivityRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	launch, err := h.db.LaunchStudyActivity(activityID, request.GroupID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if launch == nil {
+		_ = c.Error(service.ErrActivityNotFound)
+		return
+	}
+
+	c.JSON(http.StatusCreated, launch)
+}
+
+// FinishStudySession marks a study session as finished
+func (h *StudyHandler) FinishStudySession(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorizeSessionToken(c, h.db, id) {
+		return
+	}
+
+	session, err := h.db.FinishStudySession(id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if session == nil {
+		_ = c.Error(service.ErrSessionNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
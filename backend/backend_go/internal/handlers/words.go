@@ -2,99 +2,247 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
+	"pengyou-chinese/backend/internal/models"
 	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 // WordsHandler handles word-related routes
 type WordsHandler struct {
-	db *service.DBService
+	db service.Store
 }
 
 // NewWordsHandler creates a new words handler
-func NewWordsHandler(db *service.DBService) *WordsHandler {
+func NewWordsHandler(db service.Store) *WordsHandler {
 	return &WordsHandler{db: db}
 }
 
 // GetWords returns a paginated list of words
 func (h *WordsHandler) GetWords(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
+	options, ok := bindListOptions(c, validation.WordListFields)
+	if !ok {
+		return
+	}
 
-	words, total, err := h.db.GetWords(page, pageSize)
+	words, info, err := h.db.GetWords(options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": words,
-		"pagination": gin.H{
-			"current_page":   page,
-			"total_pages":    (total + pageSize - 1) / pageSize,
-			"total_items":    total,
-			"items_per_page": pageSize,
-		},
-	})
+	respondList(c, words, info)
+}
+
+// SearchWords returns words matching a free-text query, best matches first
+func (h *WordsHandler) SearchWords(c *gin.Context) {
+	var request validation.SearchWordsRequest
+	if !bindQuery(c, &request) {
+		return
+	}
+
+	options, ok := bindListOptions(c, validation.WordListFields)
+	if !ok {
+		return
+	}
+
+	words, info, err := h.db.SearchWords(request.Q, options)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	respondList(c, words, info)
 }
 
 // GetWord returns a single word by ID
 func (h *WordsHandler) GetWord(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
 	word, err := h.db.GetWord(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	if word == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+		_ = c.Error(service.ErrWordNotFound)
 		return
 	}
 
 	c.JSON(http.StatusOK, word)
 }
 
+// GetWordReviews returns a word's review history, oldest first
+func (h *WordsHandler) GetWordReviews(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	options, ok := bindListOptions(c, validation.WordReviewListFields)
+	if !ok {
+		return
+	}
+
+	reviews, info, err := h.db.GetWordReviews(id, options)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	respondList(c, reviews, info)
+}
+
 // AddWordReview adds a review for a word
 func (h *WordsHandler) AddWordReview(c *gin.Context) {
-	wordID, err := strconv.ParseInt(c.Param("word_id"), 10, 64)
+	wordID, ok := paramID(c, "word_id")
+	if !ok {
+		return
+	}
+
+	sessionID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorizeSessionToken(c, h.db, sessionID) {
+		return
+	}
+
+	var request validation.AddWordReviewRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	review := reviewItem(request)
+	review.WordID = wordID
+	review.StudySessionID = sessionID
+
+	stored, err := h.db.AddWordReview(review)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word ID"})
+		_ = c.Error(err)
 		return
 	}
 
-	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"id":               stored.ID,
+		"word_id":          stored.WordID,
+		"study_session_id": stored.StudySessionID,
+		"correct":          stored.Correct,
+		"answer":           stored.Answer,
+		"expected_answer":  stored.ExpectedAnswer,
+		"response_ms":      stored.ResponseMS,
+		"hint_used":        stored.HintUsed,
+		"grade":            stored.Grade,
+		"created_at":       stored.CreatedAt,
+	})
+}
+
+// CreateWord creates a new word, optionally attaching it to groups
+func (h *WordsHandler) CreateWord(c *gin.Context) {
+	var request validation.CreateWordRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	word, err := h.db.CreateWord(models.Word{
+		Japanese: request.Japanese,
+		Romaji:   request.Romaji,
+		English:  request.English,
+		Parts:    request.Parts,
+	}, request.GroupIDs)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, word)
+}
+
+// UpdateWord replaces a word's fields
+func (h *WordsHandler) UpdateWord(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.UpdateWordRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.updateWord(c, id, models.WordUpdate{
+		Japanese: &request.Japanese,
+		Romaji:   &request.Romaji,
+		English:  &request.English,
+		Parts:    &request.Parts,
+		GroupIDs: request.GroupIDs,
+	})
+}
+
+// PatchWord updates only the fields present in the request
+func (h *WordsHandler) PatchWord(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request validation.PatchWordRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	h.updateWord(c, id, models.WordUpdate{
+		Japanese: request.Japanese,
+		Romaji:   request.Romaji,
+		English:  request.English,
+		Parts:    request.Parts,
+		GroupIDs: request.GroupIDs,
+	})
+}
+
+func (h *WordsHandler) updateWord(c *gin.Context, id int64, update models.WordUpdate) {
+	word, err := h.db.UpdateWord(id, update)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		_ = c.Error(err)
 		return
 	}
 
-	var review struct {
-		Correct bool `json:"correct" binding:"required"`
+	if word == nil {
+		_ = c.Error(service.ErrWordNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, word)
+}
+
+// DeleteWord deletes a word and its review history
+func (h *WordsHandler) DeleteWord(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&review); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	deleted, err := h.db.DeleteWord(id)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	if err := h.db.AddWordReview(wordID, sessionID, review.Correct); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !deleted {
+		_ = c.Error(service.ErrWordNotFound)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":          true,
-		"word_id":          wordID,
-		"study_session_id": sessionID,
-		"correct":          review.Correct,
+		"success": true,
+		"word_id": id,
 	})
 }
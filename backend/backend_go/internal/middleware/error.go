@@ -2,19 +2,50 @@ package middleware
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"pengyou-chinese/backend/internal/service"
+	"pengyou-chinese/backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
+// Stable error codes for errors that do not come from the service layer.
+// Domain errors carry their own code, see service.Error.
+const (
+	CodeValidation    = "validation_error"
+	CodeNotFound      = "not_found"
+	CodeRouteNotFound = "route_not_found"
+	CodeInternal      = "internal_error"
+)
+
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Status    int               `json:"status"`
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   string            `json:"details,omitempty"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// kindResponses maps each kind of domain error onto its status and message
+var kindResponses = map[service.ErrorKind]ErrorResponse{
+	service.KindNotFound:     {Status: http.StatusNotFound, Message: "Resource not found"},
+	service.KindConflict:     {Status: http.StatusConflict, Message: "Conflict"},
+	service.KindInvalid:      {Status: http.StatusUnprocessableEntity, Message: "Invalid request"},
+	service.KindUnauthorized: {Status: http.StatusUnauthorized, Message: "Unauthorized"},
 }
 
 // ErrorHandler middleware handles errors in a standardized way
@@ -24,46 +55,149 @@ func ErrorHandler() gin.HandlerFunc {
 
 		// Only handle errors if there are any
 		if len(c.Errors) > 0 {
-			err := c.Errors.Last().Err
+			last := c.Errors.Last()
+			err := last.Err
 			var response ErrorResponse
+			var domainErr *service.Error
 
 			switch {
-			case errors.Is(err, sql.ErrNoRows):
+			case last.IsType(gin.ErrorTypeBind),
+				errors.As(err, &validator.ValidationErrors{}):
 				response = ErrorResponse{
-					Status:  http.StatusNotFound,
-					Message: "Resource not found",
-					Details: err.Error(),
+					Status:  http.StatusBadRequest,
+					Code:    CodeValidation,
+					Message: "Validation error",
+					Errors:  BindingErrors(err),
 				}
 
-			case errors.As(err, &validator.ValidationErrors{}):
+			// The outermost domain error decides the response, e.g. an invalid
+			// reference to a missing group is a 422 rather than a 404
+			case errors.As(err, &domainErr):
+				response = kindResponses[domainErr.Kind]
+				response.Code = domainErr.Code
+				response.Details = err.Error()
+
+			case errors.Is(err, sql.ErrNoRows):
 				response = ErrorResponse{
-					Status:  http.StatusBadRequest,
-					Message: "Validation error",
-					Details: err.Error(),
+					Status:  http.StatusNotFound,
+					Code:    CodeNotFound,
+					Message: "Resource not found",
 				}
 
 			default:
-				// Log unexpected errors
-				log.Printf("Unexpected error: %v", err)
+				// Log unexpected errors; the client only gets the request ID to quote
+				log.Printf("Unexpected error (request %s): %v", GetRequestID(c), err)
 				response = ErrorResponse{
 					Status:  http.StatusInternalServerError,
+					Code:    CodeInternal,
 					Message: "Internal server error",
 					Details: "An unexpected error occurred",
 				}
 			}
 
+			response.RequestID = GetRequestID(c)
 			c.JSON(response.Status, response)
 			c.Abort()
 		}
 	}
 }
 
+// NotFound responds to requests for unknown routes with the standard error response
+func NotFound() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusNotFound, ErrorResponse{
+			Status:    http.StatusNotFound,
+			Code:      CodeRouteNotFound,
+			Message:   "Route not found",
+			Details:   c.Request.Method + " " + c.Request.URL.Path,
+			RequestID: GetRequestID(c),
+		})
+	}
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
+	Line    int    `json:"line,omitempty"`
 	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
+// RegisterFieldNames makes gin's validator report fields by their json or form
+// names, so that validation errors match the request the client sent
+func RegisterFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// BindingErrors converts an error from binding or validating a request into
+// per-field validation errors
+func BindingErrors(err error) []ValidationError {
+	var fieldErr *validation.FieldError
+	var rowErrs validation.RowErrors
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var numErr *strconv.NumError
+
+	switch {
+	case errors.As(err, &fieldErr):
+		return []ValidationError{{Field: fieldErr.Field, Message: fieldErr.Message}}
+	case errors.As(err, &rowErrs):
+		validationErrors := make([]ValidationError, len(rowErrs))
+		for i, rowErr := range rowErrs {
+			validationErrors[i] = ValidationError{Line: rowErr.Line, Field: rowErr.Field, Message: rowErr.Message}
+		}
+		return validationErrors
+	case errors.As(err, &validationErrs):
+		var validationErrors []ValidationError
+		for _, err := range validationErrs {
+			validationErrors = append(validationErrors, ValidationError{
+				Field:   fieldPath(err.Namespace()),
+				Message: getValidationErrorMsg(err),
+			})
+		}
+		return validationErrors
+	case errors.As(err, &typeErr):
+		return []ValidationError{{Field: typeErr.Field, Message: fmt.Sprintf("Must be of type %s", typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []ValidationError{{Field: "body", Message: "Invalid JSON"}}
+	case errors.Is(err, io.EOF):
+		return []ValidationError{{Field: "body", Message: "Request body is required"}}
+	case errors.As(err, &numErr):
+		// Handlers name the parameter with validation.ParamError where they can
+		return []ValidationError{{Field: "query", Message: validation.ParseErrorMessage(numErr)}}
+	default:
+		return []ValidationError{{Field: "request", Message: err.Error()}}
+	}
+}
+
+// fieldPath drops the request struct and embedded structs from a validator
+// namespace, e.g. BatchReviewRequest.reviews[0].AddWordReviewRequest.grade
+// becomes reviews[0].grade. Every other field is named after its tag.
+func fieldPath(namespace string) string {
+	var parts []string
+	for _, part := range strings.Split(namespace, ".") {
+		if r := []rune(part); len(r) > 0 && !unicode.IsUpper(r[0]) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
 // ValidateRequest validates a request struct and returns formatted errors
 func ValidateRequest(obj interface{}) []ValidationError {
 	validate := validator.New()
@@ -89,12 +223,31 @@ func getValidationErrorMsg(err validator.FieldError) string {
 	case "required":
 		return "This field is required"
 	case "min":
-		return "Value is too small"
+		if isSized(err.Kind()) {
+			return fmt.Sprintf("Must contain at least %s item(s) or character(s)", err.Param())
+		}
+		return fmt.Sprintf("Must be at least %s", err.Param())
 	case "max":
-		return "Value is too large"
+		if isSized(err.Kind()) {
+			return fmt.Sprintf("Must contain at most %s item(s) or character(s)", err.Param())
+		}
+		return fmt.Sprintf("Must be at most %s", err.Param())
+	case "eq":
+		return fmt.Sprintf("Must be %q", err.Param())
+	case "oneof":
+		return fmt.Sprintf("Must be one of: %s", err.Param())
+	case "url":
+		return "Must be a valid URL"
+	case "json":
+		return "Must be valid JSON"
 	case "email":
 		return "Invalid email format"
 	default:
 		return "Invalid value"
 	}
 }
+
+// isSized reports whether min and max count the length of a value rather than compare it
+func isSized(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array
+}
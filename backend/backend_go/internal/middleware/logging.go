@@ -2,36 +2,51 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"io"
 	"log"
+	"mime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// responseWriter captures the response status code and body
+// maxLoggedBodySize bounds the request and response bodies written to the log.
+// Larger bodies are left out, and the logger never reads more of a request
+// than this, so the handlers' own size limits still apply to the rest.
+const maxLoggedBodySize = 4 << 10
+
+// responseWriter captures the response status code and the start of the body
 type responseWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
 }
 
-func (w responseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if room := maxLoggedBodySize + 1 - w.body.Len(); room > 0 {
+		w.body.Write(b[:min(len(b), room)])
+	}
 	return w.ResponseWriter.Write(b)
 }
 
-// Logger middleware logs request and response details
+// Logger middleware logs request and response details. Only JSON bodies up to
+// maxLoggedBodySize are logged; uploads, downloads and other bodies are omitted.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
 
-		// Read request body
+		// Read the start of a JSON request body, then put it back in front of the rest
 		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			// Restore the request body
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if c.Request.Body != nil && loggableBody(c.ContentType()) {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodySize+1))
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), c.Request.Body), c.Request.Body}
 		}
 
 		// Create custom response writer
@@ -47,6 +62,13 @@ func Logger() gin.HandlerFunc {
 		// Calculate duration
 		duration := time.Since(start)
 
+		// Downloads are omitted whatever their content type
+		response := w.body.Bytes()
+		responseType := w.Header().Get("Content-Type")
+		if strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+			responseType = "attachment"
+		}
+
 		// Log request details
 		log.Printf(
 			"[API] %s %s | Status: %d | Duration: %v | Request: %s | Response: %s",
@@ -54,24 +76,80 @@ func Logger() gin.HandlerFunc {
 			c.Request.URL.Path,
 			c.Writer.Status(),
 			duration,
-			string(requestBody),
-			w.body.String(),
+			loggedBody(c.ContentType(), requestBody),
+			loggedBody(responseType, response),
 		)
 	}
 }
 
-// RequestIDMiddleware adds a unique request ID to each request
+// loggableBody reports whether bodies of the content type are written to the log
+func loggableBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// loggedBody returns the body as it is written to the log
+func loggedBody(contentType string, body []byte) string {
+	switch {
+	case len(body) == 0 && contentType == "":
+		return ""
+	case !loggableBody(contentType):
+		return "(" + contentType + " body omitted)"
+	case len(body) > maxLoggedBodySize:
+		return "(body over " + strconv.Itoa(maxLoggedBodySize>>10) + " KB omitted)"
+	}
+	return string(body)
+}
+
+// RequestIDKey is the context key holding the request ID
+const RequestIDKey = "RequestID"
+
+// maxRequestIDLength bounds request IDs supplied by clients
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware adds a unique request ID to each request. A well-formed
+// X-Request-ID sent by the client is kept so that requests can be traced end to end.
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate request ID (you might want to use a more sophisticated method)
-		requestID := time.Now().UnixNano()
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
 
 		// Set request ID in header
-		c.Writer.Header().Set("X-Request-ID", string(requestID))
+		c.Writer.Header().Set("X-Request-ID", requestID)
 
 		// Add request ID to context
-		c.Set("RequestID", requestID)
+		c.Set(RequestIDKey, requestID)
 
 		c.Next()
 	}
 }
+
+// GetRequestID returns the ID assigned to the request by RequestIDMiddleware
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// newRequestID generates a random request ID, falling back to the time if the
+// system's random source fails
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts short IDs made of letters, digits, '-' and '_'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
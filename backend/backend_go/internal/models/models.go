@@ -11,6 +11,34 @@ type Word struct {
 	Parts    string `json:"parts,omitempty"` // JSON string
 }
 
+// WordUpdate represents a partial update to a word; nil fields are left unchanged
+type WordUpdate struct {
+	Japanese *string
+	Romaji   *string
+	English  *string
+	Parts    *string
+	GroupIDs *[]int64 // replaces the word's groups when set
+}
+
+// WordDetail represents a word with its statistics, groups and study history
+type WordDetail struct {
+	WordWithStats
+	Groups         []Group        `json:"groups"`
+	RecentSessions []StudySession `json:"recent_sessions"`
+	FirstStudiedAt *time.Time     `json:"first_studied_at"`
+	LastStudiedAt  *time.Time     `json:"last_studied_at"`
+}
+
+// PageInfo describes a page of a paginated list. In cursor mode the total is
+// not counted and NextCursor is set when there may be more rows.
+type PageInfo struct {
+	Page       int
+	PageSize   int
+	TotalItems int
+	CursorMode bool
+	NextCursor string
+}
+
 // Group represents a thematic group of words
 type Group struct {
 	ID        int64  `json:"id"`
@@ -18,21 +46,158 @@ type Group struct {
 	WordCount int    `json:"word_count,omitempty"`
 }
 
+// ImportWord is a word to import, with the review schedule it had in the tool
+// it comes from, if any
+type ImportWord struct {
+	Word
+	Schedule *ReviewSchedule
+}
+
+// GroupImport is a batch of words imported into the group GroupID, or into the
+// group named GroupName when GroupID is 0
+type GroupImport struct {
+	GroupID   int64
+	GroupName string
+	Words     []ImportWord
+}
+
+// ImportResult summarizes the import of a batch of words into a group. Words
+// are matched on their Japanese and English, so importing a word again updates
+// it rather than adding a duplicate.
+type ImportResult struct {
+	Group             Group `json:"group"`
+	GroupCreated      bool  `json:"group_created"`
+	Created           int   `json:"created"`
+	Updated           int   `json:"updated"`
+	Unchanged         int   `json:"unchanged"`
+	AddedToGroup      int   `json:"added_to_group"`
+	SchedulesImported int   `json:"schedules_imported,omitempty"`
+}
+
+// GroupExport is a group with its words and their review history, as handed
+// to other study tools
+type GroupExport struct {
+	Group     Group
+	Words     []Word
+	Schedules []ReviewSchedule
+	Reviews   []WordReviewItem
+}
+
+// Backup is an archive of all vocabulary, study activities and study history.
+// Records refer to each other by the IDs they had in the exporting database.
+// ID identifies the archive, so that restoring it again can tell which study
+// history it already restored.
+type Backup struct {
+	Format          string               `json:"format"`
+	Version         int                  `json:"version"`
+	ID              string               `json:"id,omitempty"`
+	ExportedAt      time.Time            `json:"exported_at"`
+	Words           []Word               `json:"words"`
+	Groups          []BackupGroup        `json:"groups"`
+	GroupWords      []BackupGroupWord    `json:"group_words"`
+	StudyActivities []StudyActivity      `json:"study_activities"`
+	StudySessions   []BackupStudySession `json:"study_sessions"`
+	Reviews         []WordReviewItem     `json:"reviews"`
+	Schedules       []ReviewSchedule     `json:"schedules"`
+}
+
+// BackupGroup is a group as archived in a Backup
+type BackupGroup struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// BackupGroupWord is a word's membership in a group as archived in a Backup
+type BackupGroupWord struct {
+	GroupID int64 `json:"group_id"`
+	WordID  int64 `json:"word_id"`
+}
+
+// BackupStudySession is a study session as archived in a Backup
+type BackupStudySession struct {
+	ID              int64      `json:"id"`
+	GroupID         int64      `json:"group_id"`
+	StudyActivityID int64      `json:"study_activity_id"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+}
+
+// RestoreCounts counts the archived records of one kind by whether restoring
+// them created a record or found a matching one that was kept
+type RestoreCounts struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+}
+
+// RestoreResult summarizes the restore of a Backup
+type RestoreResult struct {
+	Words           RestoreCounts `json:"words"`
+	Groups          RestoreCounts `json:"groups"`
+	GroupWords      RestoreCounts `json:"group_words"`
+	StudyActivities RestoreCounts `json:"study_activities"`
+	StudySessions   RestoreCounts `json:"study_sessions"`
+	Reviews         RestoreCounts `json:"reviews"`
+	Schedules       RestoreCounts `json:"schedules"`
+}
+
+// Snapshot describes a copy of the SQLite database written by an online backup
+type Snapshot struct {
+	File      string    `json:"file"`
+	Path      string    `json:"-"` // where the snapshot was written on the server, not shown to clients
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	// Removed lists the older snapshots deleted to keep the retention count
+	Removed []string `json:"removed"`
+}
+
+// Study session statuses
+const (
+	SessionStatusActive    = "active"
+	SessionStatusFinished  = "finished"
+	SessionStatusAbandoned = "abandoned"
+)
+
 // StudySession represents a learning session
 type StudySession struct {
-	ID              int64     `json:"id"`
-	GroupID         int64     `json:"group_id"`
-	CreatedAt       time.Time `json:"created_at"`
-	StudyActivityID int64     `json:"study_activity_id"`
-	GroupName       string    `json:"group_name,omitempty"`
+	ID               int64      `json:"id"`
+	GroupID          int64      `json:"group_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	StudyActivityID  int64      `json:"study_activity_id"`
+	GroupName        string     `json:"group_name,omitempty"`
+	Status           string     `json:"status"`
+	StartTime        time.Time  `json:"start_time"`
+	EndTime          *time.Time `json:"end_time"`
+	Duration         int64      `json:"duration"` // seconds
+	ReviewItemsCount int        `json:"review_items_count"`
 }
 
-// StudyActivity represents a specific study activity
+// StudyActivity represents a launchable study app in the activity catalog
 type StudyActivity struct {
-	ID              int64     `json:"id"`
-	StudySessionID  int64     `json:"study_session_id"`
-	GroupID         int64     `json:"group_id"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	LaunchURL    string    `json:"launch_url"`
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StudyActivityUpdate represents a partial update to a study activity; nil fields are left unchanged
+type StudyActivityUpdate struct {
+	Name         *string
+	Description  *string
+	ThumbnailURL *string
+	LaunchURL    *string
+	Enabled      *bool
+}
+
+// StudyLaunch represents a study session handed off to an external study app
+type StudyLaunch struct {
+	StudySession   StudySession `json:"study_session"`
+	Token          string       `json:"token"`
+	TokenExpiresAt time.Time    `json:"token_expires_at"`
+	LaunchURL      string       `json:"launch_url"`
 }
 
 // WordReviewItem represents a practice record for a word
@@ -41,9 +206,49 @@ type WordReviewItem struct {
 	WordID         int64     `json:"word_id"`
 	StudySessionID int64     `json:"study_session_id"`
 	Correct        bool      `json:"correct"`
+	Answer         string    `json:"answer,omitempty"`
+	ExpectedAnswer string    `json:"expected_answer,omitempty"`
+	ResponseMS     *int      `json:"response_ms,omitempty"`
+	HintUsed       bool      `json:"hint_used"`
+	Grade          *int      `json:"grade,omitempty"` // 0-5
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// WordReviewHistoryItem is a review of a word with the context it was made in
+type WordReviewHistoryItem struct {
+	WordReviewItem
+	SessionStatus     string `json:"session_status"`
+	GroupID           int64  `json:"group_id"`
+	GroupName         string `json:"group_name"`
+	StudyActivityID   int64  `json:"study_activity_id"`
+	StudyActivityName string `json:"study_activity_name"`
+}
+
+// ReviewResult represents the outcome of one review in a batch submission
+type ReviewResult struct {
+	Index    int    `json:"index"`
+	WordID   int64  `json:"word_id"`
+	Success  bool   `json:"success"`
+	ReviewID int64  `json:"review_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReviewSchedule represents the spaced-repetition state of a word
+type ReviewSchedule struct {
+	WordID         int64     `json:"word_id"`
+	EaseFactor     float64   `json:"ease_factor"`
+	IntervalDays   int       `json:"interval_days"`
+	Repetitions    int       `json:"repetitions"`
+	DueAt          time.Time `json:"due_at"`
+	LastReviewedAt time.Time `json:"last_reviewed_at"`
+}
+
+// DueWord represents a word that is due for review
+type DueWord struct {
+	Word
+	Schedule *ReviewSchedule `json:"schedule"` // nil for words that were never reviewed
+}
+
 // WordWithStats extends Word with statistics
 type WordWithStats struct {
 	Word
@@ -51,6 +256,12 @@ type WordWithStats struct {
 	WrongCount   int `json:"wrong_count"`
 }
 
+// SessionWord represents a word reviewed in a study session together with each attempt made in it
+type SessionWord struct {
+	WordWithStats
+	Reviews []WordReviewItem `json:"reviews"`
+}
+
 // StudyProgress represents study progress statistics
 type StudyProgress struct {
 	TotalWordsStudied    int `json:"total_words_studied"`
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/anki"
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+const (
+	// MaxAnkiImportSize is the largest Anki package accepted, in bytes
+	MaxAnkiImportSize = 100 << 20
+	// DefaultAnkiGroupName names the group of notes that are in no named deck
+	DefaultAnkiGroupName = "Anki import"
+)
+
+// AnkiFields maps the fields of a word onto the fields of Anki notes. Each
+// field is given by its name, matched ignoring case, or by its 1-based
+// position. Empty fields use the first note field with a usual name for it:
+// Japanese falls back to the first field and English to the second. Romaji
+// that no field holds is converted from the kana of the Reading field or of
+// the Japanese field's furigana.
+type AnkiFields struct {
+	Japanese string
+	Romaji   string
+	English  string
+	Parts    string
+	Reading  string
+}
+
+// Note field names recognized when AnkiFields leaves a field empty
+var (
+	ankiJapaneseNames = []string{"japanese", "expression", "kanji", "vocab", "vocabulary", "word", "front"}
+	ankiRomajiNames   = []string{"romaji"}
+	ankiEnglishNames  = []string{"english", "meaning", "definition", "translation", "back"}
+	ankiPartsNames    = []string{"parts"}
+	ankiReadingNames  = []string{"reading", "kana", "furigana", "hiragana"}
+)
+
+// furigana matches Anki's furigana syntax, 漢字[かんじ], with the space that
+// may separate it from the text before
+var furigana = regexp.MustCompile(`\s?([^\s\[\]]+)\[([^\]]*)\]`)
+
+// AnkiImports turns the notes of an Anki package into import batches: one per
+// deck, into the group named after the deck, or a single batch into the group
+// groupID when it is set. Notes that cannot be imported come back as
+// validation.RowErrors, or are left out and counted when skipInvalid is set.
+func AnkiImports(notes []anki.Note, fields AnkiFields, groupID int64, skipInvalid bool) ([]models.GroupImport, int, error) {
+	for _, field := range []struct{ name, spec string }{
+		{"japanese_field", fields.Japanese},
+		{"romaji_field", fields.Romaji},
+		{"english_field", fields.English},
+		{"parts_field", fields.Parts},
+		{"reading_field", fields.Reading},
+	} {
+		if n, err := strconv.Atoi(field.spec); err == nil && n < 1 {
+			return nil, 0, &validation.FieldError{Field: field.name, Message: "Must be a field name or a positive field number"}
+		}
+	}
+
+	if len(notes) == 0 {
+		return nil, 0, &validation.FieldError{Field: "file", Message: "Must contain at least one note"}
+	}
+	if len(notes) > MaxImportWords {
+		return nil, 0, &validation.FieldError{Field: "file", Message: fmt.Sprintf("Must contain at most %d notes", MaxImportWords)}
+	}
+
+	// Batch the notes by deck, keeping the order the decks were first seen in
+	var decks []string
+	rows := make(map[string][]importRow)
+	var rowErrs validation.RowErrors
+	skipped := 0
+	for _, note := range notes {
+		row, problems := ankiRow(note, fields)
+		if len(problems) > 0 {
+			rowErrs = append(rowErrs, problems...)
+			skipped++
+			continue
+		}
+
+		deck := ""
+		if groupID == 0 {
+			deck = strings.TrimSpace(note.Deck)
+			if deck == "" {
+				deck = DefaultAnkiGroupName
+			}
+		}
+		if _, ok := rows[deck]; !ok {
+			decks = append(decks, deck)
+		}
+		rows[deck] = append(rows[deck], row)
+	}
+
+	var imports []models.GroupImport
+	for _, deck := range decks {
+		words, problems, invalid := checkImportRows(rows[deck])
+		rowErrs = append(rowErrs, problems...)
+		skipped += invalid
+		if len(words) > 0 {
+			imports = append(imports, models.GroupImport{GroupID: groupID, GroupName: deck, Words: words})
+		}
+	}
+
+	if len(rowErrs) > 0 && !skipInvalid {
+		return nil, 0, rowErrs
+	}
+	if len(imports) == 0 {
+		return nil, 0, &validation.FieldError{Field: "file", Message: "Must contain at least one valid note"}
+	}
+	return imports, skipped, nil
+}
+
+// ankiRow reads the word and review schedule of a note
+func ankiRow(note anki.Note, fields AnkiFields) (importRow, []validation.RowError) {
+	row := importRow{path: fmt.Sprintf("notes[%d]", note.ID)}
+	var problems []validation.RowError
+
+	// field returns the plain text of the note field selected by spec or
+	// names, reporting a field that was asked for by name but is missing
+	field := func(name, spec string, names []string, position int) string {
+		value, ok := ankiField(note, spec, names, position)
+		if !ok && spec != "" {
+			problems = append(problems, row.rowError(name, fmt.Sprintf("The note has no %q field", spec)))
+		}
+		return anki.PlainText(value)
+	}
+	japanese := field("japanese", fields.Japanese, ankiJapaneseNames, 1)
+	romaji := field("romaji", fields.Romaji, ankiRomajiNames, 0)
+	english := field("english", fields.English, ankiEnglishNames, 2)
+	parts := field("parts", fields.Parts, ankiPartsNames, 0)
+	reading := field("reading", fields.Reading, ankiReadingNames, 0)
+
+	if reading == "" {
+		reading = furigana.ReplaceAllString(japanese, "$2")
+	} else {
+		reading = furigana.ReplaceAllString(reading, "$2")
+	}
+	japanese = strings.TrimSpace(furigana.ReplaceAllString(japanese, "$1"))
+	if romaji == "" {
+		romaji, _ = kanaToRomaji(strings.ReplaceAll(reading, " ", ""))
+	}
+
+	row.word = models.Word{Japanese: japanese, Romaji: romaji, English: english, Parts: parts}
+	row.schedule = ankiSchedule(note.Card)
+	return row, problems
+}
+
+// ankiField returns the value of the note field selected by spec, see
+// AnkiFields, or else of the first of names the note has or of the field at
+// the 1-based position, 0 for none
+func ankiField(note anki.Note, spec string, names []string, position int) (string, bool) {
+	if spec != "" {
+		if n, err := strconv.Atoi(spec); err == nil {
+			if n > len(note.Fields) {
+				return "", false
+			}
+			return note.Fields[n-1], true
+		}
+		names, position = []string{strings.TrimSpace(spec)}, 0
+	}
+
+	for _, name := range names {
+		for i, fieldName := range note.FieldNames {
+			if strings.EqualFold(fieldName, name) && i < len(note.Fields) {
+				return note.Fields[i], true
+			}
+		}
+	}
+	if position > 0 && position <= len(note.Fields) {
+		return note.Fields[position-1], true
+	}
+	return "", false
+}
+
+// ankiSchedule converts the scheduling state of a card into a review schedule,
+// nil for new cards. Anki counts every answer as a repetition while SM-2 only
+// counts the recalls since the last lapse, so repetitions are approximated by
+// the answers that were not lapses. Cards being learned are due again right
+// after their next step.
+func ankiSchedule(card anki.Card) *models.ReviewSchedule {
+	if card.Type == anki.CardNew || card.Due.IsZero() {
+		return nil
+	}
+
+	schedule := &models.ReviewSchedule{
+		EaseFactor:   defaultEaseFactor,
+		IntervalDays: max(card.Interval, 1),
+		DueAt:        card.Due,
+	}
+	if card.Factor > 0 {
+		schedule.EaseFactor = max(float64(card.Factor)/1000, minEaseFactor)
+	}
+
+	if card.Type == anki.CardReview {
+		schedule.Repetitions = max(card.Reps-card.Lapses, 1)
+		schedule.LastReviewedAt = card.Due.AddDate(0, 0, -schedule.IntervalDays)
+	} else {
+		schedule.LastReviewedAt = card.Due
+	}
+	if !card.LastReview.IsZero() {
+		schedule.LastReviewedAt = card.LastReview
+	}
+	return schedule
+}
+
+// Fields of the note type of exported decks
+var ankiExportFields = []string{"Japanese", "Romaji", "English", "Parts"}
+
+// AnkiDeck converts an exported group into an Anki deck with a note for each
+// word. A word's card carries its review schedule, and its review history is
+// replayed through the scheduler to give Anki the interval of each answer.
+func AnkiDeck(export *models.GroupExport) anki.Deck {
+	deck := anki.Deck{
+		Name:       export.Group.Name,
+		NoteType:   "Pengyou Japanese",
+		FieldNames: ankiExportFields,
+		BackFields: []string{"Romaji", "English"},
+	}
+
+	schedules := make(map[int64]models.ReviewSchedule, len(export.Schedules))
+	for _, schedule := range export.Schedules {
+		schedules[schedule.WordID] = schedule
+	}
+	reviews := make(map[int64][]models.WordReviewItem)
+	for _, review := range export.Reviews {
+		reviews[review.WordID] = append(reviews[review.WordID], review)
+	}
+
+	for _, word := range export.Words {
+		note := anki.ExportNote{
+			GUID:   ankiGUID(word),
+			Fields: []string{word.Japanese, word.Romaji, word.English, word.Parts},
+		}
+
+		var prev *models.ReviewSchedule
+		for _, review := range reviews[word.ID] {
+			quality := reviewQuality(review)
+			next := NextReviewSchedule(word.ID, prev, quality, review.CreatedAt)
+
+			answer := anki.Review{
+				Time:     review.CreatedAt,
+				Ease:     ankiEase(quality),
+				Interval: next.IntervalDays,
+				Factor:   int(math.Round(next.EaseFactor * 1000)),
+				Learning: prev == nil || prev.Repetitions == 0,
+			}
+			if prev != nil {
+				answer.LastInterval = prev.IntervalDays
+			}
+			if review.ResponseMS != nil {
+				answer.Duration = time.Duration(*review.ResponseMS) * time.Millisecond
+			}
+			if quality < passingQuality && !answer.Learning {
+				note.Card.Lapses++
+			}
+			note.Reviews = append(note.Reviews, answer)
+			prev = &next
+		}
+		note.Card.Reps = len(note.Reviews)
+
+		if schedule, ok := schedules[word.ID]; ok {
+			note.Card.Type = anki.CardReview
+			if schedule.Repetitions == 0 {
+				// The word was forgotten on its last review
+				note.Card.Type = anki.CardRelearning
+				if note.Card.Lapses == 0 {
+					note.Card.Type = anki.CardLearning
+				}
+			}
+			note.Card.Interval = schedule.IntervalDays
+			note.Card.Factor = int(math.Round(schedule.EaseFactor * 1000))
+			note.Card.Due = schedule.DueAt
+			note.Card.LastReview = schedule.LastReviewedAt
+		}
+
+		deck.Notes = append(deck.Notes, note)
+	}
+
+	return deck
+}
+
+// ankiGUID identifies a word across exports by its Japanese and English
+func ankiGUID(word models.Word) string {
+	sum := sha1.Sum([]byte(word.Japanese + "\x1f" + word.English))
+	return base64.RawStdEncoding.EncodeToString(sum[:])[:10]
+}
+
+// ankiEase maps an SM-2 quality onto Anki's answer buttons
+func ankiEase(quality int) int {
+	switch {
+	case quality < passingQuality:
+		return anki.EaseAgain
+	case quality == passingQuality:
+		return anki.EaseHard
+	case quality == 4:
+		return anki.EaseGood
+	default:
+		return anki.EaseEasy
+	}
+}
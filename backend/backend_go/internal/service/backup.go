@@ -0,0 +1,494 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+const (
+	// BackupFormat identifies the archives written by ExportBackup
+	BackupFormat = "pengyou-backup"
+	// BackupVersion is the version of the archive layout written by
+	// ExportBackup. Archives of this or an earlier version can be restored.
+	BackupVersion = 1
+	// MaxBackupSize is the largest backup archive accepted, in bytes
+	MaxBackupSize = 100 << 20
+)
+
+// ParseBackup reads and validates a backup archive. Problems with the archive
+// as a whole come back as a *validation.FieldError and problems with its
+// records as validation.RowErrors listing every invalid record by its path,
+// such as reviews[3].word_id.
+func ParseBackup(r io.Reader) (*models.Backup, error) {
+	var backup models.Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, &validation.FieldError{Field: "file", Message: "Must be a backup archive: " + err.Error()}
+	}
+
+	if backup.Format != BackupFormat {
+		return nil, &validation.FieldError{Field: "file", Message: fmt.Sprintf("Must be a backup archive with format %q", BackupFormat)}
+	}
+	if backup.Version > BackupVersion {
+		return nil, &validation.FieldError{
+			Field:   "file",
+			Message: fmt.Sprintf("Was exported by a newer server: archive version %d, this server reads up to version %d", backup.Version, BackupVersion),
+		}
+	}
+	if backup.Version < 1 {
+		return nil, &validation.FieldError{Field: "file", Message: fmt.Sprintf("Has an unknown archive version %d", backup.Version)}
+	}
+
+	if problems := checkBackup(&backup); len(problems) > 0 {
+		return nil, problems
+	}
+	return &backup, nil
+}
+
+// backupChecker collects the problems found in the records of a backup
+type backupChecker struct {
+	problems validation.RowErrors
+}
+
+// add reports an invalid field of the record at path
+func (c *backupChecker) add(path, field, message string) {
+	c.problems = append(c.problems, validation.RowError{Field: path + "." + field, Message: message})
+}
+
+// id records the ID of the record at path in seen, reporting IDs that are not
+// positive or were already used by another record of the same kind
+func (c *backupChecker) id(seen map[int64]string, id int64, path string) {
+	if id < 1 {
+		c.add(path, "id", "Must be a positive ID")
+		return
+	}
+	if first, ok := seen[id]; ok {
+		c.add(path, "id", "Duplicates "+first)
+		return
+	}
+	seen[id] = path
+}
+
+// reference reports a reference to a record that is not in the archive
+func (c *backupChecker) reference(seen map[int64]string, id int64, path, field, kind string) {
+	if _, ok := seen[id]; !ok {
+		c.add(path, field, fmt.Sprintf("Refers to no %s in the archive", kind))
+	}
+}
+
+// checkBackup validates the records of a backup and the references between them
+func checkBackup(backup *models.Backup) validation.RowErrors {
+	var c backupChecker
+	words := make(map[int64]string)
+	groups := make(map[int64]string)
+	activities := make(map[int64]string)
+	sessions := make(map[int64]string)
+	schedules := make(map[int64]string)
+
+	for i, word := range backup.Words {
+		row := importRow{word: word, path: fmt.Sprintf("words[%d]", i)}
+		c.problems = append(c.problems, row.validate()...)
+		c.id(words, word.ID, row.path)
+	}
+
+	for i, group := range backup.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		c.id(groups, group.ID, path)
+		if group.Name == "" {
+			c.add(path, "name", "This field is required")
+		}
+	}
+
+	for i, groupWord := range backup.GroupWords {
+		path := fmt.Sprintf("group_words[%d]", i)
+		c.reference(groups, groupWord.GroupID, path, "group_id", "group")
+		c.reference(words, groupWord.WordID, path, "word_id", "word")
+	}
+
+	for i, activity := range backup.StudyActivities {
+		path := fmt.Sprintf("study_activities[%d]", i)
+		c.id(activities, activity.ID, path)
+		if activity.Name == "" {
+			c.add(path, "name", "This field is required")
+		}
+	}
+
+	for i, session := range backup.StudySessions {
+		path := fmt.Sprintf("study_sessions[%d]", i)
+		c.id(sessions, session.ID, path)
+		c.reference(groups, session.GroupID, path, "group_id", "group")
+		c.reference(activities, session.StudyActivityID, path, "study_activity_id", "study activity")
+		switch session.Status {
+		case models.SessionStatusActive, models.SessionStatusFinished, models.SessionStatusAbandoned:
+		default:
+			c.add(path, "status", "Must be one of: active finished abandoned")
+		}
+	}
+
+	reviews := make(map[int64]string)
+	for i, review := range backup.Reviews {
+		path := fmt.Sprintf("reviews[%d]", i)
+		c.id(reviews, review.ID, path)
+		c.reference(words, review.WordID, path, "word_id", "word")
+		c.reference(sessions, review.StudySessionID, path, "study_session_id", "study session")
+		if review.Grade != nil && (*review.Grade < 0 || *review.Grade > 5) {
+			c.add(path, "grade", "Must be between 0 and 5")
+		}
+	}
+
+	for i, schedule := range backup.Schedules {
+		path := fmt.Sprintf("schedules[%d]", i)
+		c.reference(words, schedule.WordID, path, "word_id", "word")
+		if first, ok := schedules[schedule.WordID]; ok {
+			c.add(path, "word_id", "Duplicates "+first)
+		}
+		schedules[schedule.WordID] = path
+	}
+
+	return c.problems
+}
+
+// newBackup returns an empty backup stamped with the current version and time
+// and a random ID
+func newBackup() *models.Backup {
+	return &models.Backup{
+		Format:          BackupFormat,
+		Version:         BackupVersion,
+		ID:              rand.Text(),
+		ExportedAt:      time.Now().UTC().Truncate(time.Second),
+		Words:           []models.Word{},
+		Groups:          []models.BackupGroup{},
+		GroupWords:      []models.BackupGroupWord{},
+		StudyActivities: []models.StudyActivity{},
+		StudySessions:   []models.BackupStudySession{},
+		Reviews:         []models.WordReviewItem{},
+		Schedules:       []models.ReviewSchedule{},
+	}
+}
+
+// ExportBackup archives all words, groups, study activities and study history
+// as they are at a single point in time
+func (s *DBService) ExportBackup() (*models.Backup, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	backup := newBackup()
+
+	backup.Words, err = queryBackup(tx, backup.Words, `
+		SELECT id, japanese, romaji, english, COALESCE(parts, '')
+		FROM words
+		ORDER BY id
+	`, func(rows *sql.Rows) (word models.Word, err error) {
+		err = rows.Scan(&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts)
+		return word, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting words: %v", err)
+	}
+
+	backup.Groups, err = queryBackup(tx, backup.Groups, "SELECT id, name FROM groups ORDER BY id",
+		func(rows *sql.Rows) (group models.BackupGroup, err error) {
+			err = rows.Scan(&group.ID, &group.Name)
+			return group, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting groups: %v", err)
+	}
+
+	backup.GroupWords, err = queryBackup(tx, backup.GroupWords, "SELECT group_id, word_id FROM words_groups ORDER BY group_id, word_id",
+		func(rows *sql.Rows) (groupWord models.BackupGroupWord, err error) {
+			err = rows.Scan(&groupWord.GroupID, &groupWord.WordID)
+			return groupWord, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting group words: %v", err)
+	}
+
+	backup.StudyActivities, err = queryBackup(tx, backup.StudyActivities, `
+		SELECT id, name, description, thumbnail_url, launch_url, CAST(enabled AS INTEGER) as enabled, created_at
+		FROM study_activities
+		ORDER BY id
+	`, func(rows *sql.Rows) (activity models.StudyActivity, err error) {
+		err = rows.Scan(
+			&activity.ID,
+			&activity.Name,
+			&activity.Description,
+			&activity.ThumbnailURL,
+			&activity.LaunchURL,
+			&activity.Enabled,
+			&activity.CreatedAt,
+		)
+		return activity, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting study activities: %v", err)
+	}
+
+	backup.StudySessions, err = queryBackup(tx, backup.StudySessions, `
+		SELECT id, group_id, study_activity_id, status, created_at, ended_at
+		FROM study_sessions
+		ORDER BY id
+	`, func(rows *sql.Rows) (session models.BackupStudySession, err error) {
+		var endedAt sql.NullTime
+		err = rows.Scan(&session.ID, &session.GroupID, &session.StudyActivityID, &session.Status, &session.CreatedAt, &endedAt)
+		if endedAt.Valid {
+			session.EndedAt = &endedAt.Time
+		}
+		return session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting study sessions: %v", err)
+	}
+
+	backup.Reviews, err = queryBackup(tx, backup.Reviews, `
+		SELECT `+reviewItemColumns+`
+		FROM word_review_items wr
+		ORDER BY wr.id
+	`, func(rows *sql.Rows) (models.WordReviewItem, error) {
+		return scanWordReviewItem(rows)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting word reviews: %v", err)
+	}
+
+	backup.Schedules, err = queryBackup(tx, backup.Schedules, `
+		SELECT word_id, ease_factor, interval_days, repetitions, due_at, last_reviewed_at
+		FROM word_review_schedules
+		ORDER BY word_id
+	`, func(rows *sql.Rows) (schedule models.ReviewSchedule, err error) {
+		err = rows.Scan(
+			&schedule.WordID,
+			&schedule.EaseFactor,
+			&schedule.IntervalDays,
+			&schedule.Repetitions,
+			&schedule.DueAt,
+			&schedule.LastReviewedAt,
+		)
+		return schedule, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting review schedules: %v", err)
+	}
+
+	return backup, nil
+}
+
+// queryBackup appends the records scanned from the rows of a query to records
+func queryBackup[T any](tx *Tx, records []T, query string, scan func(*sql.Rows) (T, error)) ([]T, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// restoredFrom identifies the archived record with the given ID, such as a
+// study session, across restores of a backup, see RestoreBackup. Archives
+// without an ID are identified by their contents.
+func restoredFrom(backup *models.Backup, archivedID int64) string {
+	return fmt.Sprintf("%s/%d", backupID(backup), archivedID)
+}
+
+// backupID returns the ID of a backup, or a digest of its contents for
+// archives exported before backups had IDs
+func backupID(backup *models.Backup) string {
+	if backup.ID != "" {
+		return backup.ID
+	}
+	data, _ := json.Marshal(backup)
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// RestoreBackup adds the records of a backup to the database in a single
+// transaction, giving every restored record a new ID and remapping the
+// references between them. Vocabulary that already exists is kept as it is
+// rather than duplicated: words are matched on their Japanese and English,
+// and groups and study activities on their name. Study sessions and reviews
+// are always added, unless they were restored from the same archive before:
+// they are history, and records that look alike, such as two reviews of a word
+// answered in the same second, are still distinct. An archived review
+// schedule replaces a word's schedule only if it was reviewed more recently.
+func (s *DBService) RestoreBackup(backup *models.Backup) (*models.RestoreResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.RestoreResult{}
+
+	// Archived IDs mapped to the IDs of the restored records
+	words := make(map[int64]int64, len(backup.Words))
+	for _, word := range backup.Words {
+		words[word.ID], err = restoreRecord(tx, &result.Words,
+			"SELECT id FROM words WHERE japanese = ? AND english = ? ORDER BY id LIMIT 1",
+			[]any{word.Japanese, word.English},
+			"INSERT INTO words (japanese, romaji, english, parts) VALUES (?, ?, ?, ?) RETURNING id",
+			[]any{word.Japanese, word.Romaji, word.English, word.Parts},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring word: %v", err)
+		}
+	}
+
+	groups := make(map[int64]int64, len(backup.Groups))
+	for _, group := range backup.Groups {
+		groups[group.ID], err = restoreRecord(tx, &result.Groups,
+			"SELECT id FROM groups WHERE name = ? ORDER BY id LIMIT 1",
+			[]any{group.Name},
+			"INSERT INTO groups (name) VALUES (?) RETURNING id",
+			[]any{group.Name},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring group: %v", err)
+		}
+	}
+
+	for _, groupWord := range backup.GroupWords {
+		inserted, err := tx.Exec(`
+			INSERT INTO words_groups (word_id, group_id)
+			VALUES (?, ?)
+			ON CONFLICT (word_id, group_id) DO NOTHING
+		`, words[groupWord.WordID], groups[groupWord.GroupID])
+		if err != nil {
+			return nil, fmt.Errorf("error restoring group word: %v", err)
+		}
+		affected, err := inserted.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("error restoring group word: %v", err)
+		}
+		if affected > 0 {
+			result.GroupWords.Created++
+		} else {
+			result.GroupWords.Existing++
+		}
+	}
+
+	activities := make(map[int64]int64, len(backup.StudyActivities))
+	for _, activity := range backup.StudyActivities {
+		activities[activity.ID], err = restoreRecord(tx, &result.StudyActivities,
+			"SELECT id FROM study_activities WHERE name = ? ORDER BY id LIMIT 1",
+			[]any{activity.Name},
+			`
+				INSERT INTO study_activities (name, description, thumbnail_url, launch_url, enabled, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+				RETURNING id
+			`,
+			[]any{activity.Name, activity.Description, activity.ThumbnailURL, activity.LaunchURL, activity.Enabled, sqliteTimestamp(activity.CreatedAt)},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring study activity: %v", err)
+		}
+	}
+
+	sessions := make(map[int64]int64, len(backup.StudySessions))
+	for _, session := range backup.StudySessions {
+		var endedAt *string
+		if session.EndedAt != nil {
+			timestamp := sqliteTimestamp(*session.EndedAt)
+			endedAt = &timestamp
+		}
+		groupID, activityID := groups[session.GroupID], activities[session.StudyActivityID]
+		from := restoredFrom(backup, session.ID)
+		sessions[session.ID], err = restoreRecord(tx, &result.StudySessions,
+			"SELECT id FROM study_sessions WHERE restored_from = ?",
+			[]any{from},
+			`
+				INSERT INTO study_sessions (group_id, study_activity_id, status, created_at, ended_at, restored_from)
+				VALUES (?, ?, ?, ?, ?, ?)
+				RETURNING id
+			`,
+			[]any{groupID, activityID, session.Status, sqliteTimestamp(session.CreatedAt), endedAt, from},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring study session: %v", err)
+		}
+	}
+
+	for _, review := range backup.Reviews {
+		wordID, sessionID := words[review.WordID], sessions[review.StudySessionID]
+		from := restoredFrom(backup, review.ID)
+		_, err = restoreRecord(tx, &result.Reviews,
+			"SELECT id FROM word_review_items WHERE restored_from = ?",
+			[]any{from},
+			`
+				INSERT INTO word_review_items (
+					word_id, study_session_id, correct, answer, expected_answer,
+					response_ms, hint_used, grade, created_at, restored_from
+				)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING id
+			`,
+			[]any{
+				wordID,
+				sessionID,
+				review.Correct,
+				nullString(review.Answer),
+				nullString(review.ExpectedAnswer),
+				review.ResponseMS,
+				review.HintUsed,
+				review.Grade,
+				sqliteTimestamp(review.CreatedAt),
+				from,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error restoring word review: %v", err)
+		}
+	}
+
+	for _, schedule := range backup.Schedules {
+		imported, err := importReviewSchedule(tx, words[schedule.WordID], schedule)
+		if err != nil {
+			return nil, err
+		}
+		if imported {
+			result.Schedules.Created++
+		} else {
+			result.Schedules.Existing++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing restore: %v", err)
+	}
+	return result, nil
+}
+
+// restoreRecord returns the ID of the first record found by the find query,
+// or else inserts a record with the insert query and returns its ID, counting
+// which of the two happened
+func restoreRecord(tx *Tx, counts *models.RestoreCounts, find string, findArgs []any, insert string, insertArgs []any) (int64, error) {
+	var id int64
+	err := tx.QueryRow(find, findArgs...).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = tx.QueryRow(insert, insertArgs...).Scan(&id)
+		counts.Created++
+	case err == nil:
+		counts.Existing++
+	}
+	return id, err
+}
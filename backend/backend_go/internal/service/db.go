@@ -2,30 +2,27 @@ package service
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"sync"
+	"time"
 
 	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DBService handles all database operations
 type DBService struct {
-	db *sql.DB
-	mu sync.Mutex
+	db *DB
 }
 
-// NewDBService creates a new database service instance
-func NewDBService(dbPath string) (*DBService, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewDBService creates a new database service instance for the database
+// selected by a DSN, see DialectOf
+func NewDBService(dsn string) (*DBService, error) {
+	db, err := OpenDB(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("error opening database: %v", err)
-	}
-
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("error connecting to database: %v", err)
+		return nil, err
 	}
 
 	return &DBService{
@@ -33,6 +30,17 @@ func NewDBService(dbPath string) (*DBService, error) {
 	}, nil
 }
 
+// sqliteTimestamp formats a time the same way as SQLite's CURRENT_TIMESTAMP so
+// stored values compare correctly with column defaults
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// nullString stores empty strings as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
 // Close closes the database connection
 func (s *DBService) Close() error {
 	return s.db.Close()
@@ -41,20 +49,29 @@ func (s *DBService) Close() error {
 // GetLastStudySession retrieves the most recent study session
 func (s *DBService) GetLastStudySession() (*models.StudySession, error) {
 	query := `
-		SELECT s.id, s.group_id, s.created_at, s.study_activity_id, g.name as group_name
+		SELECT
+			s.id, s.group_id, s.created_at, s.study_activity_id, s.status, s.ended_at,
+			g.name as group_name,
+			COUNT(wr.id) as review_items_count
 		FROM study_sessions s
 		JOIN groups g ON s.group_id = g.id
-		ORDER BY s.created_at DESC
+		LEFT JOIN word_review_items wr ON s.id = wr.study_session_id
+		GROUP BY s.id, g.name
+		ORDER BY s.created_at DESC, s.id DESC
 		LIMIT 1
 	`
 
 	var session models.StudySession
+	var endedAt sql.NullTime
 	err := s.db.QueryRow(query).Scan(
 		&session.ID,
 		&session.GroupID,
 		&session.CreatedAt,
 		&session.StudyActivityID,
+		&session.Status,
+		&endedAt,
 		&session.GroupName,
+		&session.ReviewItemsCount,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -62,6 +79,7 @@ func (s *DBService) GetLastStudySession() (*models.StudySession, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error getting last study session: %v", err)
 	}
+	setSessionTimes(&session, endedAt, time.Now())
 
 	return &session, nil
 }
@@ -96,18 +114,18 @@ func (s *DBService) GetQuickStats() (*models.QuickStats, error) {
 		WITH review_stats AS (
 			SELECT 
 				COUNT(*) as total_reviews,
-				SUM(CASE WHEN correct = 1 THEN 1 ELSE 0 END) as correct_reviews
+				SUM(CASE WHEN correct THEN 1 ELSE 0 END) as correct_reviews
 			FROM word_review_items
 		),
 		active_groups AS (
 			SELECT COUNT(DISTINCT group_id) as count
 			FROM study_sessions
-			WHERE created_at >= datetime('now', '-30 days')
+			WHERE created_at >= ?
 		),
 		streak_days AS (
 			SELECT COUNT(DISTINCT date(created_at)) as count
 			FROM study_sessions
-			WHERE created_at >= datetime('now', '-30 days')
+			WHERE created_at >= ?
 		)
 		SELECT 
 			COALESCE(CAST(correct_reviews AS FLOAT) / NULLIF(total_reviews, 0) * 100, 0) as success_rate,
@@ -118,7 +136,8 @@ func (s *DBService) GetQuickStats() (*models.QuickStats, error) {
 	`
 
 	var stats models.QuickStats
-	err := s.db.QueryRow(query).Scan(
+	since := sqliteTimestamp(time.Now().AddDate(0, 0, -30))
+	err := s.db.QueryRow(query, since, since).Scan(
 		&stats.SuccessRate,
 		&stats.TotalStudySessions,
 		&stats.TotalActiveGroups,
@@ -131,37 +150,65 @@ func (s *DBService) GetQuickStats() (*models.QuickStats, error) {
 	return &stats, nil
 }
 
-// GetWords retrieves a paginated list of words with their statistics
-func (s *DBService) GetWords(page, pageSize int) ([]models.WordWithStats, int, error) {
-	offset := (page - 1) * pageSize
+// wordStatsColumns are the columns of wordStatsQuery read into models.WordWithStats
+const wordStatsColumns = "id, japanese, romaji, english, parts, correct_count, wrong_count"
+
+// wordStatsQuery selects words with their review statistics. join and where
+// restrict the words and may reference words as w.
+func wordStatsQuery(join, where string, args ...any) listQuery {
+	return listQuery{
+		base: `
+			SELECT
+				w.id, w.japanese, w.romaji, w.english, w.parts,
+				COALESCE(SUM(CASE WHEN wr.correct THEN 1 ELSE 0 END), 0) as correct_count,
+				COALESCE(SUM(CASE WHEN NOT wr.correct THEN 1 ELSE 0 END), 0) as wrong_count,
+				MAX(wr.created_at) as last_studied_at
+			FROM words w
+			` + join + `
+			LEFT JOIN word_review_items wr ON w.id = wr.word_id
+			` + where + `
+			GROUP BY w.id
+		`,
+		args:        args,
+		columns:     wordStatsColumns,
+		defaultSort: "id",
+	}
+}
 
-	// Get total count
-	var totalItems int
-	countQuery := "SELECT COUNT(*) FROM words"
-	if err := s.db.QueryRow(countQuery).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting words: %v", err)
+// studySessionsQuery selects study sessions with their group name and review count.
+// where restricts the sessions and may reference study_sessions as s.
+func studySessionsQuery(where string, args ...any) listQuery {
+	return listQuery{
+		base: `
+			SELECT
+				s.id, s.group_id, s.created_at, s.study_activity_id, s.status, s.ended_at,
+				g.name as group_name,
+				sa.name as activity_name,
+				(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_items_count
+			FROM study_sessions s
+			JOIN groups g ON s.group_id = g.id
+			JOIN study_activities sa ON s.study_activity_id = sa.id
+			` + where + `
+		`,
+		args:        args,
+		columns:     "id, group_id, created_at, study_activity_id, status, ended_at, group_name, review_items_count",
+		defaultSort: "created_at",
+		defaultDesc: true,
 	}
+}
 
-	// Get words with stats
-	query := `
-		SELECT 
-			w.id, w.japanese, w.romaji, w.english, w.parts,
-			COALESCE(SUM(CASE WHEN wr.correct = 1 THEN 1 ELSE 0 END), 0) as correct_count,
-			COALESCE(SUM(CASE WHEN wr.correct = 0 THEN 1 ELSE 0 END), 0) as wrong_count
-		FROM words w
-		LEFT JOIN word_review_items wr ON w.id = wr.word_id
-		GROUP BY w.id
-		ORDER BY w.id
-		LIMIT ? OFFSET ?
-	`
+// GetWords retrieves a paginated list of words with their statistics
+func (s *DBService) GetWords(options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	list := wordStatsQuery("", "")
 
-	rows, err := s.db.Query(query, pageSize, offset)
+	// Get words with stats
+	rows, err := s.queryList(list, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying words: %v", err)
+		return nil, models.PageInfo{}, fmt.Errorf("error querying words: %v", err)
 	}
 	defer rows.Close()
 
-	var words []models.WordWithStats
+	words := []models.WordWithStats{}
 	for rows.Next() {
 		var word models.WordWithStats
 		err := rows.Scan(
@@ -174,65 +221,366 @@ func (s *DBService) GetWords(page, pageSize int) ([]models.WordWithStats, int, e
 			&word.WrongCount,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("error scanning word row: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning word row: %v", err)
 		}
 		words = append(words, word)
 	}
 
-	return words, totalItems, nil
+	return words, rows.PageInfo(), nil
+}
+
+// AddWordReview adds a new word review record and reschedules the word.
+// The review is recorded at the current time. Sessions that have ended return
+// ErrSessionNotActive.
+func (s *DBService) AddWordReview(review models.WordReviewItem) (*models.WordReviewItem, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := checkReviewedSession(tx, review.StudySessionID); err != nil {
+		return nil, err
+	}
+
+	if err := checkReviewedWord(tx, review.StudySessionID, review.WordID); err != nil {
+		return nil, err
+	}
+
+	review.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	review.ID, err = insertWordReview(tx, review)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing word review: %v", err)
+	}
+
+	return &review, nil
+}
+
+// AddWordReviews records a batch of reviews for a study session in a single
+// transaction. Reviews are applied in the order they were answered, and reviews
+// of words that do not exist or are not in the session's group are reported as
+// failed without aborting the batch. Sessions that have ended return
+// ErrSessionNotActive, and answer times outside the session, see
+// checkAnswerTimes, ErrAnswerTimeOutOfRange.
+// The results are returned in the order of the given reviews.
+func (s *DBService) AddWordReviews(studySessionID int64, reviews []models.WordReviewItem) ([]models.ReviewResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	startedAt, err := checkReviewedSession(tx, studySessionID)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err = checkAnswerTimes(reviews, startedAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	order := reviewOrder(reviews)
+
+	results := make([]models.ReviewResult, len(reviews))
+	for _, i := range order {
+		review := reviews[i]
+		review.StudySessionID = studySessionID
+		results[i] = models.ReviewResult{Index: i, WordID: review.WordID}
+
+		err := checkReviewedWord(tx, studySessionID, review.WordID)
+		if errors.Is(err, ErrWordNotFound) || errors.Is(err, ErrWordNotInGroup) {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := insertWordReview(tx, review)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Success = true
+		results[i].ReviewID = id
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing word reviews: %v", err)
+	}
+
+	return results, nil
 }
 
-// AddWordReview adds a new word review record
-func (s *DBService) AddWordReview(wordID, studySessionID int64, correct bool) error {
+// checkReviewedSession verifies that a study session exists and is still
+// active, since finished and abandoned sessions take no more reviews. It
+// returns the time the session started.
+func checkReviewedSession(tx *Tx, studySessionID int64) (time.Time, error) {
+	var status string
+	var startedAt time.Time
+	err := tx.QueryRow("SELECT status, created_at FROM study_sessions WHERE id = ?", studySessionID).Scan(&status, &startedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("%w: %d", ErrSessionNotFound, studySessionID)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error checking study session: %v", err)
+	}
+	if status != models.SessionStatusActive {
+		return time.Time{}, ErrSessionNotActive
+	}
+	return startedAt, nil
+}
+
+// checkReviewedWord verifies that a word exists and belongs to the group being
+// studied in the session
+func checkReviewedWord(tx *Tx, studySessionID, wordID int64) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM words WHERE id = ?)", wordID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking word: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrWordNotFound, wordID)
+	}
+
 	query := `
-		INSERT INTO word_review_items (word_id, study_session_id, correct)
-		VALUES (?, ?, ?)
+		SELECT EXISTS(
+			SELECT 1
+			FROM study_sessions s
+			JOIN words_groups wg ON wg.group_id = s.group_id
+			WHERE s.id = ? AND wg.word_id = ?
+		)
+	`
+	if err := tx.QueryRow(query, studySessionID, wordID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking word group: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %w: word %d", ErrInvalidReference, ErrWordNotInGroup, wordID)
+	}
+
+	return nil
+}
+
+// insertWordReview stores a review at its CreatedAt time and reschedules the word
+func insertWordReview(tx *Tx, review models.WordReviewItem) (int64, error) {
+	query := `
+		INSERT INTO word_review_items (
+			word_id, study_session_id, correct, answer, expected_answer,
+			response_ms, hint_used, grade, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
 
-	_, err := s.db.Exec(query, wordID, studySessionID, correct)
+	var id int64
+	err := tx.QueryRow(query,
+		review.WordID,
+		review.StudySessionID,
+		review.Correct,
+		nullString(review.Answer),
+		nullString(review.ExpectedAnswer),
+		review.ResponseMS,
+		review.HintUsed,
+		review.Grade,
+		sqliteTimestamp(review.CreatedAt),
+	).Scan(&id)
 	if err != nil {
-		return fmt.Errorf("error adding word review: %v", err)
+		return 0, fmt.Errorf("error adding word review: %v", err)
+	}
+
+	if err := updateReviewSchedule(tx, review.WordID, reviewQuality(review), review.CreatedAt.UTC()); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// updateReviewSchedule applies a review of the given quality to the word's schedule
+func updateReviewSchedule(tx *Tx, wordID int64, quality int, now time.Time) error {
+	query := `
+		SELECT word_id, ease_factor, interval_days, repetitions, due_at, last_reviewed_at
+		FROM word_review_schedules
+		WHERE word_id = ?
+	`
+
+	var prev models.ReviewSchedule
+	err := tx.QueryRow(query, wordID).Scan(
+		&prev.WordID,
+		&prev.EaseFactor,
+		&prev.IntervalDays,
+		&prev.Repetitions,
+		&prev.DueAt,
+		&prev.LastReviewedAt,
+	)
+
+	var next models.ReviewSchedule
+	switch {
+	case err == sql.ErrNoRows:
+		next = NextReviewSchedule(wordID, nil, quality, now)
+	case err != nil:
+		return fmt.Errorf("error getting review schedule: %v", err)
+	default:
+		next = NextReviewSchedule(wordID, &prev, quality, now)
+	}
+
+	upsert := `
+		INSERT INTO word_review_schedules (word_id, ease_factor, interval_days, repetitions, due_at, last_reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(word_id) DO UPDATE SET
+			ease_factor = excluded.ease_factor,
+			interval_days = excluded.interval_days,
+			repetitions = excluded.repetitions,
+			due_at = excluded.due_at,
+			last_reviewed_at = excluded.last_reviewed_at
+	`
+
+	_, err = tx.Exec(upsert,
+		next.WordID,
+		next.EaseFactor,
+		next.IntervalDays,
+		next.Repetitions,
+		next.DueAt.Truncate(time.Second),
+		next.LastReviewedAt.Truncate(time.Second),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating review schedule: %v", err)
 	}
 
 	return nil
 }
 
-// CreateStudySession creates a new study session
+// GetDueWords retrieves words that are due for review, optionally limited to a group.
+// Words that were never reviewed are always due and are returned after overdue ones.
+func (s *DBService) GetDueWords(groupID int64, options validation.ListOptions) ([]models.DueWord, models.PageInfo, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Most overdue first by default
+	list := listQuery{
+		base: `
+			SELECT
+				w.id, w.japanese, w.romaji, w.english, w.parts,
+				rs.ease_factor, rs.interval_days, rs.repetitions, rs.due_at, rs.last_reviewed_at,
+				COALESCE(rs.due_at, '9999-12-31') as due_order
+			FROM words w
+			LEFT JOIN word_review_schedules rs ON w.id = rs.word_id
+			WHERE (rs.word_id IS NULL OR rs.due_at <= ?)
+			AND (? = 0 OR w.id IN (SELECT word_id FROM words_groups WHERE group_id = ?))
+		`,
+		args: []any{now, groupID, groupID},
+		columns: `
+			id, japanese, romaji, english, parts,
+			ease_factor, interval_days, repetitions, due_at, last_reviewed_at
+		`,
+		defaultSort: "due_order",
+	}
+
+	rows, err := s.queryList(list, options)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying due words: %v", err)
+	}
+	defer rows.Close()
+
+	words := []models.DueWord{}
+	for rows.Next() {
+		var word models.DueWord
+		var easeFactor sql.NullFloat64
+		var intervalDays, repetitions sql.NullInt64
+		var dueAt, lastReviewedAt sql.NullTime
+		err := rows.Scan(
+			&word.ID,
+			&word.Japanese,
+			&word.Romaji,
+			&word.English,
+			&word.Parts,
+			&easeFactor,
+			&intervalDays,
+			&repetitions,
+			&dueAt,
+			&lastReviewedAt,
+		)
+		if err != nil {
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning due word row: %v", err)
+		}
+		if dueAt.Valid {
+			word.Schedule = &models.ReviewSchedule{
+				WordID:         word.ID,
+				EaseFactor:     easeFactor.Float64,
+				IntervalDays:   int(intervalDays.Int64),
+				Repetitions:    int(repetitions.Int64),
+				DueAt:          dueAt.Time,
+				LastReviewedAt: lastReviewedAt.Time,
+			}
+		}
+		words = append(words, word)
+	}
+
+	return words, rows.PageInfo(), nil
+}
+
+// CreateStudySession creates a new study session for an existing group and study activity
 func (s *DBService) CreateStudySession(groupID, studyActivityID int64) (*models.StudySession, error) {
+	return createStudySession(s.db, groupID, studyActivityID)
+}
+
+// createStudySession implements CreateStudySession on a database or transaction
+func createStudySession(db SQLExecutor, groupID, studyActivityID int64) (*models.StudySession, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM groups WHERE id = ?)", groupID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking group: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+	}
+
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM study_activities WHERE id = ?)", studyActivityID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error checking study activity: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrActivityNotFound, studyActivityID)
+	}
+
 	query := `
 		INSERT INTO study_sessions (group_id, study_activity_id)
 		VALUES (?, ?)
-		RETURNING id, group_id, created_at, study_activity_id
+		RETURNING id, group_id, created_at, study_activity_id, status
 	`
 
 	var session models.StudySession
-	err := s.db.QueryRow(query, groupID, studyActivityID).Scan(
+	err = db.QueryRow(query, groupID, studyActivityID).Scan(
 		&session.ID,
 		&session.GroupID,
 		&session.CreatedAt,
 		&session.StudyActivityID,
+		&session.Status,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating study session: %v", err)
 	}
+	setSessionTimes(&session, sql.NullTime{}, time.Now())
 
 	return &session, nil
 }
 
-// GetWord retrieves a single word by ID with its statistics
-func (s *DBService) GetWord(id int64) (*models.WordWithStats, error) {
+// recentWordSessionsLimit is the number of recent study sessions returned with a word
+const recentWordSessionsLimit = 5
+
+// GetWord retrieves a single word by ID with its statistics, groups and study history
+func (s *DBService) GetWord(id int64) (*models.WordDetail, error) {
 	query := `
 		SELECT 
 			w.id, w.japanese, w.romaji, w.english, w.parts,
-			COALESCE(SUM(CASE WHEN wr.correct = 1 THEN 1 ELSE 0 END), 0) as correct_count,
-			COALESCE(SUM(CASE WHEN wr.correct = 0 THEN 1 ELSE 0 END), 0) as wrong_count
+			COALESCE(SUM(CASE WHEN wr.correct THEN 1 ELSE 0 END), 0) as correct_count,
+			COALESCE(SUM(CASE WHEN NOT wr.correct THEN 1 ELSE 0 END), 0) as wrong_count
 		FROM words w
 		LEFT JOIN word_review_items wr ON w.id = wr.word_id
 		WHERE w.id = ?
 		GROUP BY w.id
 	`
 
-	var word models.WordWithStats
+	var word models.WordDetail
 	err := s.db.QueryRow(query, id).Scan(
 		&word.ID,
 		&word.Japanese,
@@ -255,6 +603,7 @@ func (s *DBService) GetWord(id int64) (*models.WordWithStats, error) {
 		FROM groups g
 		JOIN words_groups wg ON g.id = wg.group_id
 		WHERE wg.word_id = ?
+		ORDER BY g.name, g.id
 	`
 
 	rows, err := s.db.Query(groupsQuery, id)
@@ -263,58 +612,337 @@ func (s *DBService) GetWord(id int64) (*models.WordWithStats, error) {
 	}
 	defer rows.Close()
 
-	var groups []models.Group
+	word.Groups = []models.Group{}
 	for rows.Next() {
 		var group models.Group
 		if err := rows.Scan(&group.ID, &group.Name); err != nil {
 			return nil, fmt.Errorf("error scanning group: %v", err)
 		}
-		groups = append(groups, group)
+		word.Groups = append(word.Groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting word groups: %v", err)
+	}
+
+	// First and last time the word was reviewed
+	if word.FirstStudiedAt, err = s.getWordReviewTime(id, "ASC"); err != nil {
+		return nil, err
+	}
+	if word.LastStudiedAt, err = s.getWordReviewTime(id, "DESC"); err != nil {
+		return nil, err
+	}
+
+	word.RecentSessions, err = s.getWordRecentSessions(id, recentWordSessionsLimit)
+	if err != nil {
+		return nil, err
 	}
 
 	return &word, nil
 }
 
-// GetGroups retrieves a paginated list of groups
-func (s *DBService) GetGroups(page, pageSize int) ([]models.Group, int, error) {
-	offset := (page - 1) * pageSize
+// getWordReviewTime returns the time of the earliest ("ASC") or latest ("DESC")
+// review of a word, or nil if it was never reviewed
+func (s *DBService) getWordReviewTime(wordID int64, order string) (*time.Time, error) {
+	query := fmt.Sprintf(`
+		SELECT created_at FROM word_review_items
+		WHERE word_id = ?
+		ORDER BY created_at %[1]s, id %[1]s
+		LIMIT 1
+	`, order)
 
-	// Get total count
-	var totalItems int
-	countQuery := "SELECT COUNT(*) FROM groups"
-	if err := s.db.QueryRow(countQuery).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting groups: %v", err)
+	var reviewedAt time.Time
+	err := s.db.QueryRow(query, wordID).Scan(&reviewedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting word review time: %v", err)
 	}
 
-	// Get groups with word count
+	return &reviewedAt, nil
+}
+
+// getWordRecentSessions retrieves the most recent study sessions in which a word was reviewed
+func (s *DBService) getWordRecentSessions(wordID int64, limit int) ([]models.StudySession, error) {
 	query := `
 		SELECT 
-			g.id, 
-			g.name,
-			COUNT(DISTINCT wg.word_id) as word_count
-		FROM groups g
-		LEFT JOIN words_groups wg ON g.id = wg.group_id
-		GROUP BY g.id
-		ORDER BY g.id
-		LIMIT ? OFFSET ?
+			s.id, s.group_id, s.created_at, s.study_activity_id, s.status, s.ended_at,
+			g.name as group_name,
+			(SELECT COUNT(*) FROM word_review_items WHERE study_session_id = s.id) as review_items_count
+		FROM study_sessions s
+		JOIN groups g ON s.group_id = g.id
+		WHERE s.id IN (SELECT study_session_id FROM word_review_items WHERE word_id = ?)
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT ?
 	`
 
-	rows, err := s.db.Query(query, pageSize, offset)
+	rows, err := s.db.Query(query, wordID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying word sessions: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.StudySession{}
+	for rows.Next() {
+		var session models.StudySession
+		var endedAt sql.NullTime
+		err := rows.Scan(
+			&session.ID,
+			&session.GroupID,
+			&session.CreatedAt,
+			&session.StudyActivityID,
+			&session.Status,
+			&endedAt,
+			&session.GroupName,
+			&session.ReviewItemsCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning study session row: %v", err)
+		}
+		setSessionTimes(&session, endedAt, time.Now())
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying word sessions: %v", err)
+	}
+
+	return sessions, nil
+}
+
+// GetWordReviews retrieves every review of a word, oldest first, with the
+// session, activity and group it was made in
+func (s *DBService) GetWordReviews(wordID int64, options validation.ListOptions) ([]models.WordReviewHistoryItem, models.PageInfo, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM words WHERE id = ?)", wordID).Scan(&exists); err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error checking word: %v", err)
+	}
+	if !exists {
+		return nil, models.PageInfo{}, fmt.Errorf("%w: %d", ErrWordNotFound, wordID)
+	}
+
+	list := listQuery{
+		base: `
+			SELECT ` + reviewItemColumns + `,
+				s.status as session_status, s.group_id, g.name as group_name,
+				s.study_activity_id, sa.name as study_activity_name
+			FROM word_review_items wr
+			JOIN study_sessions s ON wr.study_session_id = s.id
+			JOIN groups g ON s.group_id = g.id
+			JOIN study_activities sa ON s.study_activity_id = sa.id
+			WHERE wr.word_id = ?
+		`,
+		args: []any{wordID},
+		columns: `
+			id, word_id, study_session_id, correct, answer, expected_answer,
+			response_ms, hint_used, grade, created_at,
+			session_status, group_id, group_name, study_activity_id, study_activity_name
+		`,
+		defaultSort: "created_at",
+	}
+
+	rows, err := s.queryList(list, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying groups: %v", err)
+		return nil, models.PageInfo{}, fmt.Errorf("error querying word reviews: %v", err)
 	}
 	defer rows.Close()
 
-	var groups []models.Group
+	reviews := []models.WordReviewHistoryItem{}
+	for rows.Next() {
+		var item models.WordReviewHistoryItem
+		item.WordReviewItem, err = scanWordReviewItem(rows,
+			&item.SessionStatus,
+			&item.GroupID,
+			&item.GroupName,
+			&item.StudyActivityID,
+			&item.StudyActivityName,
+		)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		reviews = append(reviews, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying word reviews: %v", err)
+	}
+
+	return reviews, rows.PageInfo(), nil
+}
+
+// CreateWord creates a new word and attaches it to the given groups
+func (s *DBService) CreateWord(word models.Word, groupIDs []int64) (*models.WordDetail, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO words (japanese, romaji, english, parts)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`
+
+	var wordID int64
+	err = tx.QueryRow(query, word.Japanese, word.Romaji, word.English, word.Parts).Scan(&wordID)
+	if err != nil {
+		return nil, fmt.Errorf("error creating word: %v", err)
+	}
+
+	if err := addWordToGroups(tx, wordID, groupIDs); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing word: %v", err)
+	}
+
+	return s.GetWord(wordID)
+}
+
+// UpdateWord applies a partial update to a word. It returns nil if the word does not exist.
+func (s *DBService) UpdateWord(id int64, update models.WordUpdate) (*models.WordDetail, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE words SET
+			japanese = COALESCE(?, japanese),
+			romaji = COALESCE(?, romaji),
+			english = COALESCE(?, english),
+			parts = COALESCE(?, parts)
+		WHERE id = ?
+	`
+
+	result, err := tx.Exec(query, update.Japanese, update.Romaji, update.English, update.Parts, id)
+	if err != nil {
+		return nil, fmt.Errorf("error updating word: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error updating word: %v", err)
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+
+	// Replace the word's groups only when a new list was provided
+	if update.GroupIDs != nil {
+		if _, err := tx.Exec("DELETE FROM words_groups WHERE word_id = ?", id); err != nil {
+			return nil, fmt.Errorf("error removing word from groups: %v", err)
+		}
+		if err := addWordToGroups(tx, id, *update.GroupIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing word: %v", err)
+	}
+
+	return s.GetWord(id)
+}
+
+// DeleteWord deletes a word together with its group memberships and review history.
+// It returns false if the word does not exist.
+func (s *DBService) DeleteWord(id int64) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	dependents := []string{
+		"DELETE FROM words_groups WHERE word_id = ?",
+		"DELETE FROM word_review_items WHERE word_id = ?",
+		"DELETE FROM word_review_schedules WHERE word_id = ?",
+	}
+	for _, stmt := range dependents {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return false, fmt.Errorf("error deleting word dependents: %v", err)
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM words WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting word: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting word: %v", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing word deletion: %v", err)
+	}
+
+	return true, nil
+}
+
+// addWordToGroups attaches a word to each of the given groups, skipping existing memberships
+func addWordToGroups(tx *Tx, wordID int64, groupIDs []int64) error {
+	for _, groupID := range groupIDs {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM groups WHERE id = ?)", groupID).Scan(&exists); err != nil {
+			return fmt.Errorf("error checking group: %v", err)
+		}
+		if !exists {
+			return fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+		}
+
+		query := `
+			INSERT INTO words_groups (word_id, group_id)
+			VALUES (?, ?)
+			ON CONFLICT (word_id, group_id) DO NOTHING
+		`
+		if _, err := tx.Exec(query, wordID, groupID); err != nil {
+			return fmt.Errorf("error adding word to group: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GetGroups retrieves a paginated list of groups
+func (s *DBService) GetGroups(options validation.ListOptions) ([]models.Group, models.PageInfo, error) {
+	list := listQuery{
+		base: `
+			SELECT 
+				g.id, 
+				g.name,
+				COUNT(DISTINCT wg.word_id) as word_count
+			FROM groups g
+			LEFT JOIN words_groups wg ON g.id = wg.group_id
+			GROUP BY g.id
+		`,
+		columns:     "id, name, word_count",
+		defaultSort: "id",
+	}
+
+	// Get groups with word count
+	rows, err := s.queryList(list, options)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying groups: %v", err)
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
 	for rows.Next() {
 		var group models.Group
 		if err := rows.Scan(&group.ID, &group.Name, &group.WordCount); err != nil {
-			return nil, 0, fmt.Errorf("error scanning group: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning group: %v", err)
 		}
 		groups = append(groups, group)
 	}
 
-	return groups, totalItems, nil
+	return groups, rows.PageInfo(), nil
 }
 
 // GetGroup retrieves a single group by ID with statistics
@@ -342,44 +970,181 @@ func (s *DBService) GetGroup(id int64) (*models.Group, error) {
 	return &group, nil
 }
 
-// GetGroupWords retrieves words for a specific group
-func (s *DBService) GetGroupWords(groupID int64, page, pageSize int) ([]models.WordWithStats, int, error) {
-	offset := (page - 1) * pageSize
-
-	// Get total count
-	var totalItems int
-	countQuery := `
-		SELECT COUNT(DISTINCT w.id)
-		FROM words w
-		JOIN words_groups wg ON w.id = wg.word_id
-		WHERE wg.group_id = ?
+// CreateGroup creates a new, empty group
+func (s *DBService) CreateGroup(name string) (*models.Group, error) {
+	query := `
+		INSERT INTO groups (name)
+		VALUES (?)
+		RETURNING id, name
 	`
-	if err := s.db.QueryRow(countQuery, groupID).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting group words: %v", err)
+
+	var group models.Group
+	if err := s.db.QueryRow(query, name).Scan(&group.ID, &group.Name); err != nil {
+		return nil, fmt.Errorf("error creating group: %v", err)
 	}
 
-	// Get words with stats
-	query := `
-		SELECT 
-			w.id, w.japanese, w.romaji, w.english, w.parts,
-			COALESCE(SUM(CASE WHEN wr.correct = 1 THEN 1 ELSE 0 END), 0) as correct_count,
-			COALESCE(SUM(CASE WHEN wr.correct = 0 THEN 1 ELSE 0 END), 0) as wrong_count
-		FROM words w
-		JOIN words_groups wg ON w.id = wg.word_id
-		LEFT JOIN word_review_items wr ON w.id = wr.word_id
-		WHERE wg.group_id = ?
-		GROUP BY w.id
-		ORDER BY w.id
-		LIMIT ? OFFSET ?
-	`
+	return &group, nil
+}
+
+// RenameGroup changes the name of a group. It returns nil if the group does not exist.
+func (s *DBService) RenameGroup(id int64, name string) (*models.Group, error) {
+	result, err := s.db.Exec("UPDATE groups SET name = ? WHERE id = ?", name, id)
+	if err != nil {
+		return nil, fmt.Errorf("error renaming group: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error renaming group: %v", err)
+	}
+	if affected == 0 {
+		return nil, nil
+	}
 
-	rows, err := s.db.Query(query, groupID, pageSize, offset)
+	return s.GetGroup(id)
+}
+
+// DeleteGroup deletes a group, its word memberships and its study sessions.
+// The words themselves are kept. It returns false if the group does not exist.
+func (s *DBService) DeleteGroup(id int64) (bool, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying group words: %v", err)
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	dependents := []string{
+		"DELETE FROM word_review_items WHERE study_session_id IN (SELECT id FROM study_sessions WHERE group_id = ?)",
+		"DELETE FROM study_sessions WHERE group_id = ?",
+		"DELETE FROM words_groups WHERE group_id = ?",
+	}
+	for _, stmt := range dependents {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return false, fmt.Errorf("error deleting group dependents: %v", err)
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM groups WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting group: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting group: %v", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing group deletion: %v", err)
+	}
+
+	return true, nil
+}
+
+// AddWordsToGroup adds words to a group, ignoring words that are already members.
+// It returns the number of newly added words.
+func (s *DBService) AddWordsToGroup(groupID int64, wordIDs []int64) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM groups WHERE id = ?)", groupID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("error checking group: %v", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("%w: %d", ErrGroupNotFound, groupID)
+	}
+
+	added := 0
+	for _, wordID := range wordIDs {
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM words WHERE id = ?)", wordID).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("error checking word: %v", err)
+		}
+		if !exists {
+			return 0, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrWordNotFound, wordID)
+		}
+
+		query := `
+			INSERT INTO words_groups (word_id, group_id)
+			VALUES (?, ?)
+			ON CONFLICT (word_id, group_id) DO NOTHING
+		`
+		result, err := tx.Exec(query, wordID, groupID)
+		if err != nil {
+			return 0, fmt.Errorf("error adding word to group: %v", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error adding word to group: %v", err)
+		}
+		added += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing group words: %v", err)
+	}
+
+	return added, nil
+}
+
+// RemoveWordsFromGroup removes words from a group and returns the number of removed words
+func (s *DBService) RemoveWordsFromGroup(groupID int64, wordIDs []int64) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM groups WHERE id = ?)", groupID).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("error checking group: %v", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("%w: %d", ErrGroupNotFound, groupID)
+	}
+
+	removed := 0
+	for _, wordID := range wordIDs {
+		result, err := tx.Exec("DELETE FROM words_groups WHERE group_id = ? AND word_id = ?", groupID, wordID)
+		if err != nil {
+			return 0, fmt.Errorf("error removing word from group: %v", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error removing word from group: %v", err)
+		}
+		removed += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing group words: %v", err)
+	}
+
+	return removed, nil
+}
+
+// GetGroupWords retrieves words for a specific group
+func (s *DBService) GetGroupWords(groupID int64, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	list := wordStatsQuery(
+		"JOIN words_groups wg ON w.id = wg.word_id",
+		"WHERE wg.group_id = ?",
+		groupID,
+	)
+
+	// Get words with stats
+	rows, err := s.queryList(list, options)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying group words: %v", err)
 	}
 	defer rows.Close()
 
-	var words []models.WordWithStats
+	words := []models.WordWithStats{}
 	for rows.Next() {
 		var word models.WordWithStats
 		err := rows.Scan(
@@ -392,89 +1157,74 @@ func (s *DBService) GetGroupWords(groupID int64, page, pageSize int) ([]models.W
 			&word.WrongCount,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("error scanning word row: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning word row: %v", err)
 		}
 		words = append(words, word)
 	}
 
-	return words, totalItems, nil
+	return words, rows.PageInfo(), nil
 }
 
 // GetStudySessions retrieves a paginated list of study sessions
-func (s *DBService) GetStudySessions(page, pageSize int) ([]models.StudySession, int, error) {
-	offset := (page - 1) * pageSize
-
-	// Get total count
-	var totalItems int
-	countQuery := "SELECT COUNT(*) FROM study_sessions"
-	if err := s.db.QueryRow(countQuery).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting study sessions: %v", err)
-	}
+func (s *DBService) GetStudySessions(options validation.ListOptions) ([]models.StudySession, models.PageInfo, error) {
+	list := studySessionsQuery("")
 
 	// Get study sessions with group names
-	query := `
-		SELECT 
-			s.id, s.group_id, s.created_at, s.study_activity_id,
-			g.name as group_name,
-			COUNT(wr.id) as review_items_count
-		FROM study_sessions s
-		JOIN groups g ON s.group_id = g.id
-		LEFT JOIN word_review_items wr ON s.id = wr.study_session_id
-		GROUP BY s.id
-		ORDER BY s.created_at DESC
-		LIMIT ? OFFSET ?
-	`
-
-	rows, err := s.db.Query(query, pageSize, offset)
+	rows, err := s.queryList(list, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying study sessions: %v", err)
+		return nil, models.PageInfo{}, fmt.Errorf("error querying study sessions: %v", err)
 	}
 	defer rows.Close()
 
-	var sessions []models.StudySession
+	sessions := []models.StudySession{}
 	for rows.Next() {
 		var session models.StudySession
-		var reviewCount int
+		var endedAt sql.NullTime
 		err := rows.Scan(
 			&session.ID,
 			&session.GroupID,
 			&session.CreatedAt,
 			&session.StudyActivityID,
+			&session.Status,
+			&endedAt,
 			&session.GroupName,
-			&reviewCount,
+			&session.ReviewItemsCount,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("error scanning study session row: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning study session row: %v", err)
 		}
+		setSessionTimes(&session, endedAt, time.Now())
 		sessions = append(sessions, session)
 	}
 
-	return sessions, totalItems, nil
+	return sessions, rows.PageInfo(), nil
 }
 
 // GetStudySession retrieves a single study session by ID
 func (s *DBService) GetStudySession(id int64) (*models.StudySession, error) {
 	query := `
 		SELECT 
-			s.id, s.group_id, s.created_at, s.study_activity_id,
+			s.id, s.group_id, s.created_at, s.study_activity_id, s.status, s.ended_at,
 			g.name as group_name,
 			COUNT(wr.id) as review_items_count
 		FROM study_sessions s
 		JOIN groups g ON s.group_id = g.id
 		LEFT JOIN word_review_items wr ON s.id = wr.study_session_id
 		WHERE s.id = ?
-		GROUP BY s.id
+		GROUP BY s.id, g.name
 	`
 
 	var session models.StudySession
-	var reviewCount int
+	var endedAt sql.NullTime
 	err := s.db.QueryRow(query, id).Scan(
 		&session.ID,
 		&session.GroupID,
 		&session.CreatedAt,
 		&session.StudyActivityID,
+		&session.Status,
+		&endedAt,
 		&session.GroupName,
-		&reviewCount,
+		&session.ReviewItemsCount,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -482,51 +1232,48 @@ func (s *DBService) GetStudySession(id int64) (*models.StudySession, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error getting study session: %v", err)
 	}
+	setSessionTimes(&session, endedAt, time.Now())
 
 	return &session, nil
 }
 
-// GetStudySessionWords retrieves words reviewed in a study session
-func (s *DBService) GetStudySessionWords(sessionID int64, page, pageSize int) ([]models.WordWithStats, int, error) {
-	offset := (page - 1) * pageSize
-
-	// Get total count
-	var totalItems int
-	countQuery := `
-		SELECT COUNT(DISTINCT w.id)
-		FROM words w
-		JOIN word_review_items wr ON w.id = wr.word_id
-		WHERE wr.study_session_id = ?
-	`
-	if err := s.db.QueryRow(countQuery, sessionID).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting session words: %v", err)
+// GetStudySessionWords retrieves words reviewed in a study session, in the order
+// they were first reviewed, together with every attempt made in the session
+func (s *DBService) GetStudySessionWords(sessionID int64, options validation.ListOptions) ([]models.SessionWord, models.PageInfo, error) {
+	list := listQuery{
+		base: `
+			SELECT
+				w.id, w.japanese, w.romaji, w.english, w.parts,
+				COALESCE(SUM(CASE WHEN wr.correct THEN 1 ELSE 0 END), 0) as correct_count,
+				COALESCE(SUM(CASE WHEN NOT wr.correct THEN 1 ELSE 0 END), 0) as wrong_count,
+				MAX(wr.created_at) as last_studied_at,
+				sw.first_review_id
+			FROM words w
+			JOIN (
+				SELECT word_id, MIN(id) as first_review_id
+				FROM word_review_items
+				WHERE study_session_id = ?
+				GROUP BY word_id
+			) sw ON w.id = sw.word_id
+			LEFT JOIN word_review_items wr ON w.id = wr.word_id
+			GROUP BY w.id, sw.first_review_id
+		`,
+		args:        []any{sessionID},
+		columns:     wordStatsColumns,
+		defaultSort: "first_review_id",
 	}
 
-	// Get words with their review status for this session
-	query := `
-		SELECT 
-			w.id, w.japanese, w.romaji, w.english, w.parts,
-			SUM(CASE WHEN wr2.correct = 1 THEN 1 ELSE 0 END) as correct_count,
-			SUM(CASE WHEN wr2.correct = 0 THEN 1 ELSE 0 END) as wrong_count,
-			wr1.correct as session_correct
-		FROM words w
-		JOIN word_review_items wr1 ON w.id = wr1.word_id AND wr1.study_session_id = ?
-		LEFT JOIN word_review_items wr2 ON w.id = wr2.word_id
-		GROUP BY w.id
-		ORDER BY wr1.created_at
-		LIMIT ? OFFSET ?
-	`
-
-	rows, err := s.db.Query(query, sessionID, pageSize, offset)
+	// Get words with their overall stats
+	rows, err := s.queryList(list, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying session words: %v", err)
+		return nil, models.PageInfo{}, fmt.Errorf("error querying session words: %v", err)
 	}
 	defer rows.Close()
 
-	var words []models.WordWithStats
+	words := []models.SessionWord{}
+	index := make(map[int64]int)
 	for rows.Next() {
-		var word models.WordWithStats
-		var sessionCorrect bool
+		var word models.SessionWord
 		err := rows.Scan(
 			&word.ID,
 			&word.Japanese,
@@ -535,21 +1282,139 @@ func (s *DBService) GetStudySessionWords(sessionID int64, page, pageSize int) ([
 			&word.Parts,
 			&word.CorrectCount,
 			&word.WrongCount,
-			&sessionCorrect,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("error scanning word row: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning word row: %v", err)
 		}
+		word.Reviews = []models.WordReviewItem{}
+		index[word.ID] = len(words)
 		words = append(words, word)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying session words: %v", err)
+	}
+
+	if len(words) == 0 {
+		return words, rows.PageInfo(), nil
+	}
+
+	// Attach every attempt made in this session to its word
+	reviewsQuery := `
+		SELECT ` + reviewItemColumns + `
+		FROM word_review_items wr
+		WHERE wr.study_session_id = ?
+		ORDER BY wr.created_at, wr.id
+	`
+
+	reviewRows, err := s.db.Query(reviewsQuery, sessionID)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying session reviews: %v", err)
+	}
+	defer reviewRows.Close()
+
+	for reviewRows.Next() {
+		review, err := scanWordReviewItem(reviewRows)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		if i, ok := index[review.WordID]; ok {
+			words[i].Reviews = append(words[i].Reviews, review)
+		}
+	}
 
-	return words, totalItems, nil
+	return words, rows.PageInfo(), nil
+}
+
+// reviewItemColumns selects the word_review_items (aliased wr) columns read by scanWordReviewItem.
+// Flags are selected as integers so that lists filter and sort them as numbers on every database.
+const reviewItemColumns = `
+	wr.id, wr.word_id, wr.study_session_id, CAST(wr.correct AS INTEGER) as correct, wr.answer, wr.expected_answer,
+	wr.response_ms, CAST(wr.hint_used AS INTEGER) as hint_used, wr.grade, wr.created_at
+`
+
+// scanWordReviewItem scans a row selected with reviewItemColumns, followed by
+// any extra columns into the given destinations
+func scanWordReviewItem(row interface{ Scan(...any) error }, extra ...any) (models.WordReviewItem, error) {
+	var review models.WordReviewItem
+	var answer, expectedAnswer sql.NullString
+	var responseMS, grade sql.NullInt64
+	dest := []any{
+		&review.ID,
+		&review.WordID,
+		&review.StudySessionID,
+		&review.Correct,
+		&answer,
+		&expectedAnswer,
+		&responseMS,
+		&review.HintUsed,
+		&grade,
+		&review.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return review, fmt.Errorf("error scanning word review row: %v", err)
+	}
+
+	review.Answer = answer.String
+	review.ExpectedAnswer = expectedAnswer.String
+	if responseMS.Valid {
+		ms := int(responseMS.Int64)
+		review.ResponseMS = &ms
+	}
+	if grade.Valid {
+		g := int(grade.Int64)
+		review.Grade = &g
+	}
+
+	return review, nil
+}
+
+// GetStudyActivities retrieves a paginated list of study activities, optionally only enabled ones
+func (s *DBService) GetStudyActivities(enabledOnly bool, options validation.ListOptions) ([]models.StudyActivity, models.PageInfo, error) {
+	list := listQuery{
+		base: `
+			SELECT
+				id, name, description, thumbnail_url, launch_url,
+				CAST(enabled AS INTEGER) as enabled, created_at
+			FROM study_activities
+			WHERE (NOT ? OR enabled)
+		`,
+		args:        []any{enabledOnly},
+		columns:     "id, name, description, thumbnail_url, launch_url, enabled, created_at",
+		defaultSort: "id",
+	}
+
+	rows, err := s.queryList(list, options)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error querying study activities: %v", err)
+	}
+	defer rows.Close()
+
+	activities := []models.StudyActivity{}
+	for rows.Next() {
+		var activity models.StudyActivity
+		err := rows.Scan(
+			&activity.ID,
+			&activity.Name,
+			&activity.Description,
+			&activity.ThumbnailURL,
+			&activity.LaunchURL,
+			&activity.Enabled,
+			&activity.CreatedAt,
+		)
+		if err != nil {
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning study activity row: %v", err)
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, rows.PageInfo(), nil
 }
 
 // GetStudyActivity retrieves a study activity by ID
 func (s *DBService) GetStudyActivity(id int64) (*models.StudyActivity, error) {
 	query := `
-		SELECT id, study_session_id, group_id, created_at
+		SELECT id, name, description, thumbnail_url, launch_url, enabled, created_at
 		FROM study_activities
 		WHERE id = ?
 	`
@@ -557,8 +1422,11 @@ func (s *DBService) GetStudyActivity(id int64) (*models.StudyActivity, error) {
 	var activity models.StudyActivity
 	err := s.db.QueryRow(query, id).Scan(
 		&activity.ID,
-		&activity.StudySessionID,
-		&activity.GroupID,
+		&activity.Name,
+		&activity.Description,
+		&activity.ThumbnailURL,
+		&activity.LaunchURL,
+		&activity.Enabled,
 		&activity.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -571,59 +1439,135 @@ func (s *DBService) GetStudyActivity(id int64) (*models.StudyActivity, error) {
 	return &activity, nil
 }
 
-// GetStudyActivitySessions retrieves study sessions for a specific activity
-func (s *DBService) GetStudyActivitySessions(activityID int64, page, pageSize int) ([]models.StudySession, int, error) {
-	offset := (page - 1) * pageSize
-
-	// Get total count
-	var totalItems int
-	countQuery := `
-		SELECT COUNT(*)
-		FROM study_sessions
-		WHERE study_activity_id = ?
+// CreateStudyActivity adds a new activity to the catalog
+func (s *DBService) CreateStudyActivity(activity models.StudyActivity) (*models.StudyActivity, error) {
+	query := `
+		INSERT INTO study_activities (name, description, thumbnail_url, launch_url, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
 	`
-	if err := s.db.QueryRow(countQuery, activityID).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error counting activity sessions: %v", err)
+
+	var id int64
+	err := s.db.QueryRow(query,
+		activity.Name,
+		activity.Description,
+		activity.ThumbnailURL,
+		activity.LaunchURL,
+		activity.Enabled,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("error creating study activity: %v", err)
 	}
 
-	// Get sessions with group names
+	return s.GetStudyActivity(id)
+}
+
+// UpdateStudyActivity applies a partial update to a study activity.
+// It returns nil if the activity does not exist.
+func (s *DBService) UpdateStudyActivity(id int64, update models.StudyActivityUpdate) (*models.StudyActivity, error) {
 	query := `
-		SELECT 
-			s.id, s.group_id, s.created_at, s.study_activity_id,
-			g.name as group_name,
-			COUNT(wr.id) as review_items_count
-		FROM study_sessions s
-		JOIN groups g ON s.group_id = g.id
-		LEFT JOIN word_review_items wr ON s.id = wr.study_session_id
-		WHERE s.study_activity_id = ?
-		GROUP BY s.id
-		ORDER BY s.created_at DESC
-		LIMIT ? OFFSET ?
+		UPDATE study_activities SET
+			name = COALESCE(?, name),
+			description = COALESCE(?, description),
+			thumbnail_url = COALESCE(?, thumbnail_url),
+			launch_url = COALESCE(?, launch_url),
+			enabled = COALESCE(?, enabled)
+		WHERE id = ?
 	`
 
-	rows, err := s.db.Query(query, activityID, pageSize, offset)
+	result, err := s.db.Exec(query,
+		update.Name,
+		update.Description,
+		update.ThumbnailURL,
+		update.LaunchURL,
+		update.Enabled,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error updating study activity: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error updating study activity: %v", err)
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+
+	return s.GetStudyActivity(id)
+}
+
+// DeleteStudyActivity removes an activity from the catalog. Activities that
+// already have study sessions cannot be deleted and should be disabled instead.
+// It returns false if the activity does not exist.
+func (s *DBService) DeleteStudyActivity(id int64) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var inUse bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM study_sessions WHERE study_activity_id = ?)", id).Scan(&inUse)
+	if err != nil {
+		return false, fmt.Errorf("error checking study activity sessions: %v", err)
+	}
+	if inUse {
+		return false, ErrActivityInUse
+	}
+
+	result, err := tx.Exec("DELETE FROM study_activities WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting study activity: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting study activity: %v", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing study activity deletion: %v", err)
+	}
+
+	return true, nil
+}
+
+// GetStudyActivitySessions retrieves study sessions for a specific activity
+func (s *DBService) GetStudyActivitySessions(activityID int64, options validation.ListOptions) ([]models.StudySession, models.PageInfo, error) {
+	list := studySessionsQuery("WHERE s.study_activity_id = ?", activityID)
+
+	// Get sessions with group names
+	rows, err := s.queryList(list, options)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error querying activity sessions: %v", err)
+		return nil, models.PageInfo{}, fmt.Errorf("error querying activity sessions: %v", err)
 	}
 	defer rows.Close()
 
-	var sessions []models.StudySession
+	sessions := []models.StudySession{}
 	for rows.Next() {
 		var session models.StudySession
-		var reviewCount int
+		var endedAt sql.NullTime
 		err := rows.Scan(
 			&session.ID,
 			&session.GroupID,
 			&session.CreatedAt,
 			&session.StudyActivityID,
+			&session.Status,
+			&endedAt,
 			&session.GroupName,
-			&reviewCount,
+			&session.ReviewItemsCount,
 		)
 		if err != nil {
-			return nil, 0, fmt.Errorf("error scanning session row: %v", err)
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning session row: %v", err)
 		}
+		setSessionTimes(&session, endedAt, time.Now())
 		sessions = append(sessions, session)
 	}
 
-	return sessions, totalItems, nil
+	return sessions, rows.PageInfo(), nil
 }
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Dialect identifies the database engine behind a DBService. Queries are
+// written for SQLite and adapted to the engine where the two differ.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// DialectOf returns the dialect selected by a DSN. postgres:// and
+// postgresql:// URLs select PostgreSQL; anything else is a SQLite file path.
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// driverName returns the database/sql driver used for the dialect
+func (d Dialect) driverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+	return "sqlite3"
+}
+
+// rebind rewrites the ? placeholders of a query into the dialect's syntax,
+// leaving string literals, quoted identifiers and comments alone
+func (d Dialect) rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+1])
+			i += end
+		case c == '?':
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// like returns the case-insensitive LIKE operator. SQLite's LIKE already
+// ignores case.
+func (d Dialect) like() string {
+	if d == Postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// cursorColumn selects a list column for a cursor. In SQLite the unary plus
+// keeps timestamps as the stored text instead of converting them to times.
+func (d Dialect) cursorColumn(column string) string {
+	if d == Postgres {
+		return column
+	}
+	return "+" + column
+}
+
+// migrationsGlob matches the dialect's migration files in db.Migrations
+func (d Dialect) migrationsGlob() string {
+	if d == Postgres {
+		return "migrations/postgres/*.sql"
+	}
+	return "migrations/*.sql"
+}
+
+// tablesQuery lists the tables of the database. SQLite's FTS5 virtual tables
+// come first since dropping them also drops their shadow tables.
+func (d Dialect) tablesQuery() string {
+	if d == Postgres {
+		return `
+			SELECT tablename
+			FROM pg_tables
+			WHERE schemaname = current_schema()
+			ORDER BY tablename
+		`
+	}
+	return `
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY sql LIKE 'CREATE VIRTUAL TABLE%' DESC, name
+	`
+}
+
+// tableExistsQuery counts the tables with the name given as its argument
+func (d Dialect) tableExistsQuery() string {
+	if d == Postgres {
+		return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	return "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+// columnExistsQuery counts the columns of a table with a name, given as its arguments
+func (d Dialect) columnExistsQuery() string {
+	if d == Postgres {
+		return `
+			SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?
+		`
+	}
+	return "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+}
+
+// dropTable returns the statement that drops a table
+func (d Dialect) dropTable(table string) string {
+	if d == Postgres {
+		return fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", quoteIdentifier(table))
+	}
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteIdentifier(table))
+}
+
+// resetIDs returns the statements that restart the IDs of emptied tables at 1
+func (d Dialect) resetIDs(tables ...string) []string {
+	if d == Postgres {
+		var statements []string
+		for _, table := range tables {
+			statements = append(statements, fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), 1, false)", table))
+		}
+		return statements
+	}
+	return []string{fmt.Sprintf("DELETE FROM sqlite_sequence WHERE name IN ('%s')", strings.Join(tables, "', '"))}
+}
+
+// syncIDs returns the statements that continue a table's IDs after rows were
+// inserted with explicit IDs. SQLite does this by itself.
+func (d Dialect) syncIDs(table string) []string {
+	if d == Postgres {
+		return []string{fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)",
+			table,
+		)}
+	}
+	return nil
+}
+
+// DB is a database handle for queries written for SQLite, which it adapts to
+// the dialect of the database it is connected to
+type DB struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// OpenDB connects to the database selected by a DSN, see DialectOf
+func OpenDB(dsn string) (*DB, error) {
+	dialect := DialectOf(dsn)
+	if !slices.Contains(sql.Drivers(), dialect.driverName()) {
+		return nil, fmt.Errorf("error opening database: PostgreSQL support is not compiled in, build with -tags postgres")
+	}
+
+	if dialect == SQLite {
+		// Enforce the schema's foreign keys, which SQLite leaves off by default
+		if strings.Contains(dsn, "?") {
+			dsn += "&_foreign_keys=on"
+		} else {
+			dsn += "?_foreign_keys=on"
+		}
+	}
+
+	db, err := sql.Open(dialect.driverName(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to database: %v", err)
+	}
+
+	return &DB{db: db, dialect: dialect}, nil
+}
+
+// Dialect returns the dialect of the database
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// Exec executes a query without returning any rows
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.db.Exec(db.dialect.rebind(query), args...)
+}
+
+// Query executes a query that returns rows
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.db.Query(db.dialect.rebind(query), args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.db.QueryRow(db.dialect.rebind(query), args...)
+}
+
+// Begin starts a transaction
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, dialect: db.dialect}, nil
+}
+
+// Close closes the database
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+// Tx is a transaction on a DB
+type Tx struct {
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+// Dialect returns the dialect of the database
+func (tx *Tx) Dialect() Dialect {
+	return tx.dialect
+}
+
+// Exec executes a query without returning any rows
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.tx.Exec(tx.dialect.rebind(query), args...)
+}
+
+// Query executes a query that returns rows
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.tx.Query(tx.dialect.rebind(query), args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.tx.QueryRow(tx.dialect.rebind(query), args...)
+}
+
+// Commit commits the transaction
+func (tx *Tx) Commit() error {
+	return tx.tx.Commit()
+}
+
+// Rollback aborts the transaction
+func (tx *Tx) Rollback() error {
+	return tx.tx.Rollback()
+}
@@ -0,0 +1,76 @@
+package service
+
+// ErrorKind classifies domain errors so that they can be mapped onto responses
+type ErrorKind int
+
+const (
+	// KindNotFound means a resource in the request URL does not exist
+	KindNotFound ErrorKind = iota + 1
+	// KindConflict means the request conflicts with the current state of a resource
+	KindConflict
+	// KindInvalid means the request is well-formed but cannot be applied
+	KindInvalid
+	// KindUnauthorized means the request's credentials were rejected
+	KindUnauthorized
+)
+
+// Error is a domain error with a stable, machine-readable code. Its message is
+// safe to show to clients; errors of any other type are treated as internal.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func notFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func invalid(code, message string) *Error {
+	return &Error{Kind: KindInvalid, Code: code, Message: message}
+}
+
+func unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+var (
+	// ErrGroupNotFound is returned when an operation references a group that does not exist
+	ErrGroupNotFound = notFound("group_not_found", "group not found")
+	// ErrWordNotFound is returned when an operation references a word that does not exist
+	ErrWordNotFound = notFound("word_not_found", "word not found")
+	// ErrActivityNotFound is returned when an operation references a study activity that does not exist
+	ErrActivityNotFound = notFound("study_activity_not_found", "study activity not found")
+	// ErrSessionNotFound is returned when an operation references a study session that does not exist
+	ErrSessionNotFound = notFound("study_session_not_found", "study session not found")
+	// ErrNoStudySessions is returned when there is no last study session to show
+	ErrNoStudySessions = notFound("no_study_sessions", "no study sessions found")
+	// ErrWordNotInGroup is returned when a word is reviewed in a session for a group it does not belong to
+	ErrWordNotInGroup = invalid("word_not_in_group", "word is not in the study session's group")
+	// ErrInvalidReference wraps errors caused by a request body that references
+	// missing or mismatched records, as opposed to a missing resource in the URL.
+	// It must be wrapped first so that it takes precedence over the wrapped error.
+	ErrInvalidReference = invalid("invalid_reference", "invalid reference")
+	// ErrAnswerTimeOutOfRange is returned for reviews answered in the future or before their study session started
+	ErrAnswerTimeOutOfRange = invalid("answered_at_out_of_range", "answer time is outside the study session")
+	// ErrActivityInUse is returned when deleting a study activity that has study sessions
+	ErrActivityInUse = conflict("study_activity_in_use", "study activity has study sessions, disable it instead")
+	// ErrActivityDisabled is returned when launching a study activity that is disabled
+	ErrActivityDisabled = conflict("study_activity_disabled", "study activity is disabled")
+	// ErrSessionNotActive is returned when closing or reviewing in a study session that has already ended
+	ErrSessionNotActive = conflict("study_session_not_active", "study session has already ended")
+	// ErrSnapshotsUnsupported is returned when taking a snapshot of a database other than SQLite
+	ErrSnapshotsUnsupported = conflict("snapshots_unsupported", "online backups are only supported for SQLite databases")
+	// ErrSessionTokenRequired is returned when a launched study session is used without its session token
+	ErrSessionTokenRequired = unauthorized("session_token_required", "study session was launched and requires its session token")
+	// ErrInvalidSessionToken is returned for study session tokens that are unknown or expired
+	ErrInvalidSessionToken = unauthorized("invalid_session_token", "invalid or expired session token")
+)
@@ -0,0 +1,553 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// Vocabulary file formats accepted by ParseImport
+const (
+	ImportCSV  = "csv"
+	ImportTSV  = "tsv"
+	ImportJSON = "json"
+)
+
+const (
+	// MaxImportSize is the largest vocabulary file accepted, in bytes
+	MaxImportSize = 10 << 20
+	// MaxImportWords is the most words a vocabulary file may hold
+	MaxImportWords = 10000
+)
+
+// ImportFormatOf returns the format matching a file name's extension, or "" if
+// there is none
+func ImportFormatOf(filename string) string {
+	switch format := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), ".")); format {
+	case ImportCSV, ImportTSV, ImportJSON:
+		return format
+	default:
+		return ""
+	}
+}
+
+// ImportColumns maps the fields of a word onto the columns of a CSV or TSV
+// file. Each column is given by its header name, matched ignoring case, or by
+// its 1-based position. Empty fields use the column named after the field, or
+// the first four columns in field order if the file has no header row. Parts
+// is optional unless it is set.
+type ImportColumns struct {
+	Japanese string
+	Romaji   string
+	English  string
+	Parts    string
+}
+
+// ImportFile is a parsed vocabulary file
+type ImportFile struct {
+	// GroupName is the group named by a JSON seed file, empty for CSV and TSV
+	GroupName string
+	Words     []models.ImportWord
+}
+
+// ParseImport reads and validates a vocabulary file: a CSV or TSV file with
+// the columns described by columns, or a JSON file shaped like a SeedFile.
+// Problems with the request come back as a *validation.FieldError and problems
+// with the file's rows as validation.RowErrors listing every invalid row.
+func ParseImport(r io.Reader, format string, header bool, columns ImportColumns) (*ImportFile, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading import file: %v", err)
+	}
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	var file *ImportFile
+	var rows []importRow
+	switch format {
+	case ImportCSV, ImportTSV:
+		file = &ImportFile{}
+		rows, err = parseDelimited(content, format == ImportTSV, header, columns)
+	case ImportJSON:
+		file, rows, err = parseSeedFile(content)
+	default:
+		return nil, &validation.FieldError{Field: "format", Message: "Must be one of: csv tsv json"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, &validation.FieldError{Field: "file", Message: "Must contain at least one word"}
+	}
+	if len(rows) > MaxImportWords {
+		return nil, &validation.FieldError{Field: "file", Message: fmt.Sprintf("Must contain at most %d words", MaxImportWords)}
+	}
+
+	var rowErrs validation.RowErrors
+	file.Words, rowErrs, _ = checkImportRows(rows)
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
+	return file, nil
+}
+
+// importRow is a word read from a vocabulary file together with where it was
+// found: a line number, or the path of an item such as words[3] in a JSON file
+type importRow struct {
+	word     models.Word
+	schedule *models.ReviewSchedule
+	line     int
+	path     string
+}
+
+// checkImportRows validates rows and returns the words of the valid ones, the
+// problems found and the number of invalid rows. A row repeating the Japanese
+// and English of an earlier row is invalid.
+func checkImportRows(rows []importRow) ([]models.ImportWord, validation.RowErrors, int) {
+	var words []models.ImportWord
+	var rowErrs validation.RowErrors
+	invalid := 0
+	seen := make(map[[2]string]importRow)
+	for _, row := range rows {
+		problems := row.validate()
+		if first, ok := seen[[2]string{row.word.Japanese, row.word.English}]; ok && len(problems) == 0 {
+			problems = append(problems, row.rowError("japanese", "Duplicates "+first.location()))
+		}
+		if len(problems) > 0 {
+			rowErrs = append(rowErrs, problems...)
+			invalid++
+			continue
+		}
+		seen[[2]string{row.word.Japanese, row.word.English}] = row
+		words = append(words, models.ImportWord{Word: row.word, Schedule: row.schedule})
+	}
+	return words, rowErrs, invalid
+}
+
+// rowError reports an invalid field of the row
+func (row importRow) rowError(field, message string) validation.RowError {
+	if row.line > 0 {
+		return validation.RowError{Line: row.line, Field: field, Message: message}
+	}
+	return validation.RowError{Field: row.path + "." + field, Message: message}
+}
+
+// location describes where the row was found
+func (row importRow) location() string {
+	if row.line > 0 {
+		return fmt.Sprintf("line %d", row.line)
+	}
+	return row.path
+}
+
+// validate checks the fields of the row's word
+func (row importRow) validate() []validation.RowError {
+	var problems []validation.RowError
+	for _, field := range []struct{ name, value string }{
+		{"japanese", row.word.Japanese},
+		{"romaji", row.word.Romaji},
+		{"english", row.word.English},
+	} {
+		if field.value == "" {
+			problems = append(problems, row.rowError(field.name, "This field is required"))
+		}
+	}
+	if row.word.Parts != "" && !json.Valid([]byte(row.word.Parts)) {
+		problems = append(problems, row.rowError("parts", "Must be valid JSON"))
+	}
+	return problems
+}
+
+// parseDelimited reads the rows of a CSV or TSV file. Blank lines are skipped.
+func parseDelimited(content []byte, tabs, header bool, columns ImportColumns) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	if tabs {
+		// TSV files rarely quote their fields, so stray quotes are kept as text
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	var names map[string]int
+	var headerWidth int
+	if header {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		names = make(map[string]int)
+		for i, name := range record {
+			names[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		headerWidth = len(record)
+	}
+
+	japanese, err := resolveColumn("japanese_column", columns.Japanese, "japanese", 1, names, true)
+	if err != nil {
+		return nil, err
+	}
+	romaji, err := resolveColumn("romaji_column", columns.Romaji, "romaji", 2, names, true)
+	if err != nil {
+		return nil, err
+	}
+	english, err := resolveColumn("english_column", columns.English, "english", 3, names, true)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := resolveColumn("parts_column", columns.Parts, "parts", 4, names, columns.Parts != "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns given by number are checked against the header row, or the first
+	// row of a file without one, so that a wrong number is reported once
+	// rather than as a missing value on every row
+	selected := []selectedColumn{
+		{"japanese_column", japanese, true},
+		{"romaji_column", romaji, true},
+		{"english_column", english, true},
+		{"parts_column", parts, columns.Parts != ""},
+	}
+	if header {
+		if err := checkColumnCount(selected, headerWidth); err != nil {
+			return nil, err
+		}
+	}
+	checked := header
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(column int) string {
+			if column < 0 || column >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[column])
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if !checked {
+			if err := checkColumnCount(selected, len(record)); err != nil {
+				return nil, err
+			}
+			checked = true
+		}
+
+		rows = append(rows, importRow{
+			word: models.Word{
+				Japanese: field(japanese),
+				Romaji:   field(romaji),
+				English:  field(english),
+				Parts:    field(parts),
+			},
+			line: line,
+		})
+	}
+
+	return rows, nil
+}
+
+// resolveColumn returns the 0-based index of the column selected by spec, see
+// ImportColumns, or -1 if an optional column is not in the file
+func resolveColumn(field, spec, name string, position int, names map[string]int, required bool) (int, error) {
+	if spec != "" {
+		if n, err := strconv.Atoi(spec); err == nil {
+			if n < 1 {
+				return 0, &validation.FieldError{Field: field, Message: "Must be a column name or a positive column number"}
+			}
+			return n - 1, nil
+		}
+		name = strings.ToLower(strings.TrimSpace(spec))
+	} else if names == nil {
+		return position - 1, nil
+	}
+
+	if names == nil {
+		return 0, &validation.FieldError{Field: field, Message: "Must be a column number when the file has no header row"}
+	}
+	if column, ok := names[name]; ok {
+		return column, nil
+	}
+	if !required {
+		return -1, nil
+	}
+	return 0, &validation.FieldError{Field: field, Message: fmt.Sprintf("The header row has no %q column", name)}
+}
+
+// selectedColumn is the 0-based index of the column a word field is read from
+type selectedColumn struct {
+	field    string
+	index    int
+	required bool
+}
+
+// checkColumnCount verifies that the required columns are among the first
+// count columns of the file
+func checkColumnCount(columns []selectedColumn, count int) error {
+	for _, column := range columns {
+		if column.required && column.index >= count {
+			return &validation.FieldError{
+				Field:   column.field,
+				Message: fmt.Sprintf("Column %d is out of range, the file has %d columns", column.index+1, count),
+			}
+		}
+	}
+	return nil
+}
+
+// csvError reports a malformed CSV or TSV line
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return validation.RowErrors{{Line: parseErr.Line, Field: "file", Message: parseErr.Err.Error()}}
+	}
+	return fmt.Errorf("error reading import file: %v", err)
+}
+
+// parseSeedFile reads the rows of a JSON file shaped like a SeedFile
+func parseSeedFile(content []byte) (*ImportFile, []importRow, error) {
+	var seedFile SeedFile
+	if err := json.Unmarshal(content, &seedFile); err != nil {
+		return nil, nil, &validation.FieldError{Field: "file", Message: "Must be a JSON seed file: " + err.Error()}
+	}
+
+	rows := make([]importRow, len(seedFile.Words))
+	for i, word := range seedFile.Words {
+		rows[i] = importRow{
+			word: models.Word{
+				Japanese: strings.TrimSpace(word.Japanese),
+				Romaji:   strings.TrimSpace(word.Romaji),
+				English:  strings.TrimSpace(word.English),
+				Parts:    strings.TrimSpace(word.Parts),
+			},
+			path: fmt.Sprintf("words[%d]", i),
+		}
+	}
+
+	return &ImportFile{GroupName: strings.TrimSpace(seedFile.Group.Name)}, rows, nil
+}
+
+// ImportWords imports each batch of words into its group in a single
+// transaction. A batch without a group ID goes into the group with its group
+// name, which is created if there is none. Words are matched on their Japanese
+// and English: existing words get the imported romaji and parts instead of
+// being added again. An imported review schedule replaces the word's schedule
+// only if it was reviewed more recently.
+func (s *DBService) ImportWords(imports []models.GroupImport) ([]models.ImportResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]models.ImportResult, len(imports))
+	for i, batch := range imports {
+		result := &results[i]
+		groupID, groupName := batch.GroupID, batch.GroupName
+		if groupID > 0 {
+			err := tx.QueryRow("SELECT name FROM groups WHERE id = ?", groupID).Scan(&groupName)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error getting group: %v", err)
+			}
+		} else {
+			err := tx.QueryRow("SELECT id FROM groups WHERE name = ? ORDER BY id LIMIT 1", groupName).Scan(&groupID)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = tx.QueryRow("INSERT INTO groups (name) VALUES (?) RETURNING id", groupName).Scan(&groupID)
+				result.GroupCreated = true
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error getting group: %v", err)
+			}
+		}
+
+		seeder := &seeder{conn: tx, out: io.Discard}
+		for _, word := range batch.Words {
+			seedWord := SeedWord{Japanese: word.Japanese, Romaji: word.Romaji, English: word.English, Parts: word.Parts}
+			wordID, err := seeder.seedGroupWord(groupID, groupName, seedWord)
+			if err != nil {
+				return nil, err
+			}
+
+			if word.Schedule != nil {
+				imported, err := importReviewSchedule(tx, wordID, *word.Schedule)
+				if err != nil {
+					return nil, err
+				}
+				if imported {
+					result.SchedulesImported++
+				}
+			}
+		}
+
+		result.Group.ID = groupID
+		result.Created = seeder.report.Words.Created
+		result.Updated = seeder.report.Words.Updated
+		result.Unchanged = seeder.report.Words.Unchanged
+		result.AddedToGroup = seeder.report.GroupWords.Created
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing import: %v", err)
+	}
+
+	for i := range results {
+		group, err := s.GetGroup(results[i].Group.ID)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Group = *group
+	}
+	return results, nil
+}
+
+// importReviewSchedule stores a word's review schedule unless the word's own
+// schedule was reviewed at the same time or later. It reports whether the
+// schedule was stored.
+func importReviewSchedule(tx *Tx, wordID int64, schedule models.ReviewSchedule) (bool, error) {
+	query := `
+		INSERT INTO word_review_schedules (word_id, ease_factor, interval_days, repetitions, due_at, last_reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(word_id) DO UPDATE SET
+			ease_factor = excluded.ease_factor,
+			interval_days = excluded.interval_days,
+			repetitions = excluded.repetitions,
+			due_at = excluded.due_at,
+			last_reviewed_at = excluded.last_reviewed_at
+		WHERE excluded.last_reviewed_at > word_review_schedules.last_reviewed_at
+	`
+
+	result, err := tx.Exec(query,
+		wordID,
+		schedule.EaseFactor,
+		schedule.IntervalDays,
+		schedule.Repetitions,
+		schedule.DueAt.UTC().Truncate(time.Second),
+		schedule.LastReviewedAt.UTC().Truncate(time.Second),
+	)
+	if err != nil {
+		return false, fmt.Errorf("error importing review schedule: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error importing review schedule: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// ExportGroup retrieves a group with its words, their review schedules and all
+// their reviews in the order they were made. It returns nil if the group does
+// not exist.
+func (s *DBService) ExportGroup(id int64) (*models.GroupExport, error) {
+	group, err := s.GetGroup(id)
+	if err != nil || group == nil {
+		return nil, err
+	}
+	export := &models.GroupExport{Group: *group}
+
+	rows, err := s.db.Query(`
+		SELECT w.id, w.japanese, w.romaji, w.english, COALESCE(w.parts, '')
+		FROM words w
+		JOIN words_groups wg ON w.id = wg.word_id
+		WHERE wg.group_id = ?
+		ORDER BY w.id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group words: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word models.Word
+		if err := rows.Scan(&word.ID, &word.Japanese, &word.Romaji, &word.English, &word.Parts); err != nil {
+			return nil, fmt.Errorf("error scanning word row: %v", err)
+		}
+		export.Words = append(export.Words, word)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying group words: %v", err)
+	}
+
+	rows, err = s.db.Query(`
+		SELECT rs.word_id, rs.ease_factor, rs.interval_days, rs.repetitions, rs.due_at, rs.last_reviewed_at
+		FROM word_review_schedules rs
+		JOIN words_groups wg ON rs.word_id = wg.word_id
+		WHERE wg.group_id = ?
+		ORDER BY rs.word_id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying review schedules: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schedule models.ReviewSchedule
+		err := rows.Scan(
+			&schedule.WordID,
+			&schedule.EaseFactor,
+			&schedule.IntervalDays,
+			&schedule.Repetitions,
+			&schedule.DueAt,
+			&schedule.LastReviewedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning review schedule row: %v", err)
+		}
+		export.Schedules = append(export.Schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying review schedules: %v", err)
+	}
+
+	rows, err = s.db.Query(`
+		SELECT `+reviewItemColumns+`
+		FROM word_review_items wr
+		JOIN words_groups wg ON wr.word_id = wg.word_id
+		WHERE wg.group_id = ?
+		ORDER BY wr.created_at, wr.id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying word reviews: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		review, err := scanWordReviewItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		export.Reviews = append(export.Reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying word reviews: %v", err)
+	}
+
+	return export, nil
+}
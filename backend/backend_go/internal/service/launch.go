@@ -0,0 +1,154 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+)
+
+// SessionTokenTTL is how long a launched study app may use its session token
+const SessionTokenTTL = 2 * time.Hour
+
+// launcher is the part of a store needed to launch a study activity
+type launcher interface {
+	GetStudyActivity(id int64) (*models.StudyActivity, error)
+	GetGroup(id int64) (*models.Group, error)
+	createLaunchedSession(groupID, studyActivityID int64, token string, expiresAt time.Time) (*models.StudySession, error)
+}
+
+// LaunchStudyActivity starts a study session for the given activity and group and
+// mints a session token for the external study app. The returned launch URL is the
+// activity's URL with session_id, group_id and token added to its query string.
+func (s *DBService) LaunchStudyActivity(activityID, groupID int64) (*models.StudyLaunch, error) {
+	return launchStudyActivity(s, activityID, groupID)
+}
+
+// launchStudyActivity implements LaunchStudyActivity for any store
+func launchStudyActivity(s launcher, activityID, groupID int64) (*models.StudyLaunch, error) {
+	activity, err := s.GetStudyActivity(activityID)
+	if err != nil {
+		return nil, err
+	}
+	if activity == nil {
+		return nil, nil
+	}
+	if !activity.Enabled {
+		return nil, ErrActivityDisabled
+	}
+
+	launchURL, err := url.Parse(activity.LaunchURL)
+	if err != nil || launchURL.Scheme == "" {
+		return nil, fmt.Errorf("study activity %d has an invalid launch URL %q", activityID, activity.LaunchURL)
+	}
+
+	group, err := s.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+	}
+
+	token, expiresAt, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.createLaunchedSession(groupID, activityID, token, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	session.GroupName = group.Name
+
+	params := launchURL.Query()
+	params.Set("session_id", strconv.FormatInt(session.ID, 10))
+	params.Set("group_id", strconv.FormatInt(groupID, 10))
+	launchURL.RawQuery = params.Encode()
+
+	return &models.StudyLaunch{
+		StudySession:   *session,
+		Token:          token,
+		TokenExpiresAt: expiresAt,
+		LaunchURL:      launchURL.String(),
+	}, nil
+}
+
+// newSessionToken generates a random session token and its expiry time
+func newSessionToken() (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("error generating session token: %v", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(SessionTokenTTL).Truncate(time.Second)
+	return hex.EncodeToString(buf), expiresAt, nil
+}
+
+// createLaunchedSession creates a study session together with its session
+// token in one transaction, so that a launch never leaves a session behind
+// that no study app can use
+func (s *DBService) createLaunchedSession(groupID, studyActivityID int64, token string, expiresAt time.Time) (*models.StudySession, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	session, err := createStudySession(tx, groupID, studyActivityID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO study_session_tokens (token, study_session_id, expires_at)
+		VALUES (?, ?, ?)
+	`
+	if _, err := tx.Exec(query, token, session.ID, expiresAt); err != nil {
+		return nil, fmt.Errorf("error creating session token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+	return session, nil
+}
+
+// ValidateSessionToken checks the token sent for a study session. Sessions
+// started by LaunchStudyActivity require a token minted for them that has not
+// expired: a missing token gives ErrSessionTokenRequired and a wrong or
+// expired one ErrInvalidSessionToken. Other sessions need no token, but a
+// token sent for them is still rejected.
+func (s *DBService) ValidateSessionToken(sessionID int64, token string) error {
+	query := `
+		SELECT
+			EXISTS(SELECT 1 FROM study_session_tokens WHERE study_session_id = ?),
+			EXISTS(
+				SELECT 1 FROM study_session_tokens
+				WHERE token = ? AND study_session_id = ? AND expires_at > ?
+			)
+	`
+
+	var launched, valid bool
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := s.db.QueryRow(query, sessionID, token, sessionID, now).Scan(&launched, &valid); err != nil {
+		return fmt.Errorf("error validating session token: %v", err)
+	}
+
+	return checkSessionToken(token, launched, valid)
+}
+
+// checkSessionToken decides whether a token is accepted for a study session
+// that was or was not launched, given whether it matches a live token
+func checkSessionToken(token string, launched, valid bool) error {
+	switch {
+	case token == "" && launched:
+		return ErrSessionTokenRequired
+	case token != "" && !valid:
+		return ErrInvalidSessionToken
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// filterOperators maps filter operators onto SQL comparisons. Contains uses
+// the dialect's case-insensitive LIKE.
+var filterOperators = map[string]string{
+	validation.OpEq:  "=",
+	validation.OpNe:  "!=",
+	validation.OpGt:  ">",
+	validation.OpGte: ">=",
+	validation.OpLt:  "<",
+	validation.OpLte: "<=",
+}
+
+// listQuery wraps a base SELECT whose output columns are named after the
+// whitelisted list fields so that they can be filtered and sorted. Every base
+// query has an id column, which breaks ties in the sort order.
+type listQuery struct {
+	base        string
+	args        []any
+	columns     string
+	defaultSort string
+	defaultDesc bool
+}
+
+// queryList runs a list query for one page. In page mode the total is counted
+// and the page is read with LIMIT/OFFSET; in cursor mode the rows after the
+// cursor are read together with one more row, which only shows that there is
+// a next page and is never returned.
+func (s *DBService) queryList(q listQuery, options validation.ListOptions) (*listRows, error) {
+	page, pageSize := options.Page, options.PageSize
+	info := models.PageInfo{Page: page, PageSize: pageSize, CursorMode: options.CursorMode}
+	sortBy, desc := q.sort(options)
+	where, args := q.where(options, sortBy, desc, s.db.dialect)
+	order := fmt.Sprintf("%s %s %s, id %s", quoteIdentifier(sortBy), direction(desc), nullsOrder(desc), direction(desc))
+
+	columns, limit, offset := q.columns, pageSize, 0
+	if options.CursorMode {
+		info.Page = 0
+
+		// Each row carries its cursor, and the row after the page tells
+		// whether the last one becomes the next cursor
+		columns += ", " + s.db.dialect.cursorColumn(quoteIdentifier(sortBy)) + ", id"
+		limit++
+	} else {
+		offset = (page - 1) * pageSize
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS list %s", q.base, where)
+		if err := s.db.QueryRow(countQuery, args...).Scan(&info.TotalItems); err != nil {
+			return nil, fmt.Errorf("error counting rows: %v", err)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM (%s) AS list %s ORDER BY %s LIMIT ? OFFSET ?", columns, q.base, where, order)
+	rows, err := s.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listRows{Rows: rows, info: info, options: options, left: pageSize}, nil
+}
+
+// listRows reads the rows of one page of a list query. In cursor mode it scans
+// each row's cursor along with the caller's columns and stops before the extra
+// row, setting the next cursor when that row exists.
+type listRows struct {
+	*sql.Rows
+	info    models.PageInfo
+	options validation.ListOptions
+	left    int
+	last    validation.Cursor
+}
+
+// Next prepares the next row of the page for Scan
+func (r *listRows) Next() bool {
+	if r.left == 0 {
+		if r.options.CursorMode && r.Rows.Next() {
+			// Timestamps are carried as text in the format they are stored in
+			cursor := r.last
+			if t, ok := cursor.Value.(time.Time); ok {
+				cursor.Value = sqliteTimestamp(t)
+			}
+			cursor.SortBy, cursor.Desc = r.options.SortBy, r.options.Desc
+			r.info.NextCursor = cursor.Encode()
+		}
+		return false
+	}
+	r.left--
+	return r.Rows.Next()
+}
+
+// Scan copies the caller's columns of the current row into dest
+func (r *listRows) Scan(dest ...any) error {
+	if r.options.CursorMode {
+		dest = append(dest, &r.last.Value, &r.last.ID)
+	}
+	return r.Rows.Scan(dest...)
+}
+
+// PageInfo describes the page. The next cursor is only known once every row
+// of the page has been read.
+func (r *listRows) PageInfo() models.PageInfo {
+	return r.info
+}
+
+// sort returns the column and direction the list is ordered by
+func (q listQuery) sort(options validation.ListOptions) (string, bool) {
+	if options.SortBy == "" {
+		return q.defaultSort, q.defaultDesc
+	}
+	return options.SortBy, options.Desc
+}
+
+// where builds the WHERE clause for the filters and, in cursor mode, the rows
+// after the cursor. It returns the base arguments followed by the clause's.
+func (q listQuery) where(options validation.ListOptions, sortBy string, desc bool, d Dialect) (string, []any) {
+	args := append([]any{}, q.args...)
+
+	var conditions []string
+	for _, filter := range options.Filters {
+		column := quoteIdentifier(filter.Field)
+		op := filterOperators[filter.Op]
+		if filter.ValueField != "" {
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", column, op, quoteIdentifier(filter.ValueField)))
+			continue
+		}
+
+		value, placeholder := filter.Value, "?"
+		switch v := value.(type) {
+		case time.Time:
+			value = sqliteTimestamp(v)
+		case float64:
+			// Compare numbers exactly against integer and real columns alike
+			placeholder = "CAST(? AS NUMERIC)"
+		}
+		if filter.Op == validation.OpContains {
+			value = "%" + escapeLike(fmt.Sprint(value)) + "%"
+			op, placeholder = d.like(), `? ESCAPE '\'`
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", column, op, placeholder))
+		args = append(args, value)
+	}
+
+	if after := options.After; after != nil {
+		condition, cursorArgs := keysetCondition(quoteIdentifier(sortBy), desc, after)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// keysetCondition selects the rows that sort after the cursor. Lists sort
+// NULLs as SQLite does by default, so they come before every value ascending
+// and after every value descending.
+func keysetCondition(column string, desc bool, after *validation.Cursor) (string, []any) {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+
+	if after.Value == nil {
+		if desc {
+			return fmt.Sprintf("(%s IS NULL AND id %s ?)", column, cmp), []any{after.ID}
+		}
+		return fmt.Sprintf("(%s IS NOT NULL OR id %s ?)", column, cmp), []any{after.ID}
+	}
+
+	condition := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp)
+	if desc {
+		condition = fmt.Sprintf("(%s OR %s IS NULL)", condition, column)
+	}
+	return condition, []any{after.Value, after.Value, after.ID}
+}
+
+// direction returns the SQL sort direction
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// nullsOrder places NULLs first ascending and last descending, which is
+// SQLite's default and has to be spelled out for PostgreSQL
+func nullsOrder(desc bool) string {
+	if desc {
+		return "NULLS LAST"
+	}
+	return "NULLS FIRST"
+}
+
+// quoteIdentifier quotes a whitelisted column name
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,1088 @@
+package service
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// memorySession is a study session as stored by MemoryStore
+type memorySession struct {
+	ID              int64
+	GroupID         int64
+	StudyActivityID int64
+	Status          string
+	CreatedAt       time.Time
+	EndedAt         *time.Time
+	RestoredFrom    string // archived session restored into this one, see restoredFrom
+}
+
+// memoryToken is a session token as stored by MemoryStore
+type memoryToken struct {
+	SessionID int64
+	ExpiresAt time.Time
+}
+
+// MemoryStore is a Store that keeps all data in memory. It behaves like the
+// SQLite store and is meant for tests and throwaway instances.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	words      map[int64]*models.Word
+	groups     map[int64]*models.Group
+	groupWords map[int64]map[int64]bool // group ID -> word IDs
+	sessions   map[int64]*memorySession
+	activities map[int64]*models.StudyActivity
+	reviews    map[int64]*models.WordReviewItem
+	schedules  map[int64]models.ReviewSchedule
+	tokens     map[string]memoryToken
+
+	// reviewSources holds the archived review each restored review came from, see restoredFrom
+	reviewSources map[int64]string
+
+	// lastIDs holds the last ID assigned in each table, like sqlite_sequence
+	lastIDs map[string]int64
+}
+
+// NewMemoryStore creates an empty in-memory store. Call FullReset to load the seed data.
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{}
+	m.clear()
+	return m
+}
+
+// clear removes all data
+func (m *MemoryStore) clear() {
+	m.words = make(map[int64]*models.Word)
+	m.groups = make(map[int64]*models.Group)
+	m.groupWords = make(map[int64]map[int64]bool)
+	m.activities = make(map[int64]*models.StudyActivity)
+	m.lastIDs = make(map[string]int64)
+	m.clearHistory()
+}
+
+// clearHistory removes all study sessions, reviews and review schedules
+func (m *MemoryStore) clearHistory() {
+	m.sessions = make(map[int64]*memorySession)
+	m.reviews = make(map[int64]*models.WordReviewItem)
+	m.schedules = make(map[int64]models.ReviewSchedule)
+	m.tokens = make(map[string]memoryToken)
+	m.reviewSources = make(map[int64]string)
+	delete(m.lastIDs, "study_sessions")
+	delete(m.lastIDs, "word_review_items")
+}
+
+// nextID assigns the next ID in a table
+func (m *MemoryStore) nextID(table string) int64 {
+	m.lastIDs[table]++
+	return m.lastIDs[table]
+}
+
+// memoryNow returns the current time at the precision SQLite stores timestamps with
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// sortedIDs returns the keys of a map in ascending order
+func sortedIDs[V any](items map[int64]V) []int64 {
+	ids := make([]int64, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Close releases the store; the data is lost
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// ResetHistory deletes all study sessions, review items and review schedules
+// while keeping words, groups and study activities
+func (m *MemoryStore) ResetHistory() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clearHistory()
+	return nil
+}
+
+// FullReset replaces all data with the embedded seed data
+func (m *MemoryStore) FullReset() error {
+	activityFiles, groupFiles, err := seedFiles()
+	if err != nil {
+		return err
+	}
+
+	var activities []ActivitySeedFile
+	for _, file := range activityFiles {
+		var seedFile ActivitySeedFile
+		if err := readSeedFile(file, &seedFile); err != nil {
+			return err
+		}
+		activities = append(activities, seedFile)
+	}
+
+	var groups []SeedFile
+	for _, file := range groupFiles {
+		var seedFile SeedFile
+		if err := readSeedFile(file, &seedFile); err != nil {
+			return err
+		}
+		groups = append(groups, seedFile)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clear()
+	now := memoryNow()
+	for _, seedFile := range activities {
+		for _, activity := range seedFile.Activities {
+			id := int64(activity.ID)
+			m.activities[id] = &models.StudyActivity{
+				ID:           id,
+				Name:         activity.Name,
+				Description:  activity.Description,
+				ThumbnailURL: activity.ThumbnailURL,
+				LaunchURL:    activity.LaunchURL,
+				Enabled:      true,
+				CreatedAt:    now,
+			}
+			m.lastIDs["study_activities"] = max(m.lastIDs["study_activities"], id)
+		}
+	}
+
+	for _, seedFile := range groups {
+		groupID := m.nextID("groups")
+		m.groups[groupID] = &models.Group{ID: groupID, Name: seedFile.Group.Name}
+		m.groupWords[groupID] = make(map[int64]bool)
+		for _, word := range seedFile.Words {
+			wordID := m.nextID("words")
+			m.words[wordID] = &models.Word{
+				ID:       wordID,
+				Japanese: word.Japanese,
+				Romaji:   word.Romaji,
+				English:  word.English,
+				Parts:    word.Parts,
+			}
+			m.groupWords[groupID][wordID] = true
+		}
+	}
+
+	return nil
+}
+
+// GetLastStudySession retrieves the most recent study session
+func (m *MemoryStore) GetLastStudySession() (*models.StudySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var last *memorySession
+	for _, session := range m.sessions {
+		if last == nil || session.CreatedAt.After(last.CreatedAt) ||
+			session.CreatedAt.Equal(last.CreatedAt) && session.ID > last.ID {
+			last = session
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+
+	session := m.studySession(last, time.Now())
+	return &session, nil
+}
+
+// GetStudyProgress retrieves study progress statistics
+func (m *MemoryStore) GetStudyProgress() (*models.StudyProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	studied := make(map[int64]bool)
+	for _, review := range m.reviews {
+		studied[review.WordID] = true
+	}
+
+	return &models.StudyProgress{
+		TotalWordsStudied:   len(studied),
+		TotalAvailableWords: len(m.words),
+	}, nil
+}
+
+// GetQuickStats retrieves dashboard statistics
+func (m *MemoryStore) GetQuickStats() (*models.QuickStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stats models.QuickStats
+	correct := 0
+	for _, review := range m.reviews {
+		if review.Correct {
+			correct++
+		}
+	}
+	if len(m.reviews) > 0 {
+		stats.SuccessRate = float64(correct) / float64(len(m.reviews)) * 100
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -30)
+	activeGroups := make(map[int64]bool)
+	studyDays := make(map[string]bool)
+	for _, session := range m.sessions {
+		if session.CreatedAt.Before(since) {
+			continue
+		}
+		activeGroups[session.GroupID] = true
+		studyDays[session.CreatedAt.Format("2006-01-02")] = true
+	}
+
+	stats.TotalStudySessions = len(m.sessions)
+	stats.TotalActiveGroups = len(activeGroups)
+	stats.StudyStreakDays = len(studyDays)
+	return &stats, nil
+}
+
+// memoryWordStats holds a word's review statistics
+type memoryWordStats struct {
+	correct      int
+	wrong        int
+	firstStudied *time.Time
+	lastStudied  *time.Time
+}
+
+// wordStats computes the review statistics of every reviewed word
+func (m *MemoryStore) wordStats() map[int64]*memoryWordStats {
+	stats := make(map[int64]*memoryWordStats)
+	for _, id := range sortedIDs(m.reviews) {
+		review := m.reviews[id]
+		s := stats[review.WordID]
+		if s == nil {
+			s = &memoryWordStats{}
+			stats[review.WordID] = s
+		}
+		if review.Correct {
+			s.correct++
+		} else {
+			s.wrong++
+		}
+		createdAt := review.CreatedAt
+		if s.firstStudied == nil || createdAt.Before(*s.firstStudied) {
+			s.firstStudied = &createdAt
+		}
+		if s.lastStudied == nil || !createdAt.Before(*s.lastStudied) {
+			s.lastStudied = &createdAt
+		}
+	}
+	return stats
+}
+
+// wordWithStats combines a word with its statistics
+func wordWithStats(word *models.Word, stats *memoryWordStats) models.WordWithStats {
+	result := models.WordWithStats{Word: *word}
+	if stats != nil {
+		result.CorrectCount = stats.correct
+		result.WrongCount = stats.wrong
+	}
+	return result
+}
+
+// wordRow makes a word list row with the fields of validation.WordListFields
+func wordRow[T any](item T, word models.WordWithStats, stats *memoryWordStats) memoryRow[T] {
+	var lastStudied *time.Time
+	if stats != nil {
+		lastStudied = stats.lastStudied
+	}
+	return memoryRow[T]{
+		item: item,
+		id:   word.ID,
+		fields: map[string]any{
+			"id":              word.ID,
+			"japanese":        word.Japanese,
+			"romaji":          word.Romaji,
+			"english":         word.English,
+			"correct_count":   word.CorrectCount,
+			"wrong_count":     word.WrongCount,
+			"last_studied_at": lastStudied,
+		},
+	}
+}
+
+// wordList lists the given words with their statistics
+func (m *MemoryStore) wordList(ids []int64) memoryList[models.WordWithStats] {
+	stats := m.wordStats()
+	list := memoryList[models.WordWithStats]{defaultSort: "id"}
+	for _, id := range ids {
+		word := wordWithStats(m.words[id], stats[id])
+		list.rows = append(list.rows, wordRow(word, word, stats[id]))
+	}
+	return list
+}
+
+// GetWords retrieves a paginated list of words with their statistics
+func (m *MemoryStore) GetWords(options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	words, info := m.wordList(sortedIDs(m.words)).page(options)
+	return words, info, nil
+}
+
+// SearchWords finds words whose Japanese, romaji or English text starts with
+// the query terms. Matches in Japanese and romaji rank above English ones.
+func (m *MemoryStore) SearchWords(q string, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return []models.WordWithStats{}, models.PageInfo{Page: options.Page, PageSize: options.PageSize, CursorMode: options.CursorMode}, nil
+	}
+
+	stats := m.wordStats()
+	list := memoryList[models.WordWithStats]{defaultSort: "score"}
+	for _, id := range sortedIDs(m.words) {
+		word := m.words[id]
+		score, ok := searchScore(word, terms)
+		if !ok {
+			continue
+		}
+		item := wordWithStats(word, stats[id])
+		row := wordRow(item, item, stats[id])
+		row.fields["score"] = score
+		list.rows = append(list.rows, row)
+	}
+
+	words, info := list.page(options)
+	return words, info, nil
+}
+
+// searchScore matches a word against search terms. Like bm25 the score is
+// lower for better matches. It returns false if a term does not match.
+func searchScore(word *models.Word, terms [][]string) (float64, bool) {
+	fields := []struct {
+		tokens []string
+		weight float64
+	}{
+		{searchTokens(word.Japanese), 2},
+		{searchTokens(word.Romaji), 2},
+		{searchTokens(word.English), 1},
+	}
+
+	score := 0.0
+	for _, alternatives := range terms {
+		matched := false
+		for _, field := range fields {
+			for _, alternative := range alternatives {
+				if matchesPrefix(field.tokens, searchTokens(alternative)) {
+					score -= field.weight
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// searchTokens splits text into lowercase tokens like the FTS5 unicode61 tokenizer
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matchesPrefix reports whether a phrase occurs in tokens with its last token
+// matching as a prefix
+func matchesPrefix(tokens, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		last := len(phrase) - 1
+		if slices.Equal(tokens[i:i+last], phrase[:last]) && strings.HasPrefix(tokens[i+last], phrase[last]) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWord retrieves a single word by ID with its statistics, groups and study history
+func (m *MemoryStore) GetWord(id int64) (*models.WordDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.wordDetail(id), nil
+}
+
+// wordDetail builds the detail of a word, or returns nil if it does not exist
+func (m *MemoryStore) wordDetail(id int64) *models.WordDetail {
+	word, ok := m.words[id]
+	if !ok {
+		return nil
+	}
+
+	stats := m.wordStats()[id]
+	detail := &models.WordDetail{
+		WordWithStats:  wordWithStats(word, stats),
+		Groups:         []models.Group{},
+		RecentSessions: []models.StudySession{},
+	}
+	if stats != nil {
+		detail.FirstStudiedAt = stats.firstStudied
+		detail.LastStudiedAt = stats.lastStudied
+	}
+
+	for _, groupID := range sortedIDs(m.groupWords) {
+		if m.groupWords[groupID][id] {
+			group := m.groups[groupID]
+			detail.Groups = append(detail.Groups, models.Group{ID: group.ID, Name: group.Name})
+		}
+	}
+	slices.SortStableFunc(detail.Groups, func(a, b models.Group) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// Most recent sessions the word was reviewed in
+	var sessions []*memorySession
+	seen := make(map[int64]bool)
+	for _, review := range m.reviews {
+		if review.WordID == id && !seen[review.StudySessionID] {
+			seen[review.StudySessionID] = true
+			sessions = append(sessions, m.sessions[review.StudySessionID])
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *memorySession) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	now := time.Now()
+	for _, session := range sessions[:min(len(sessions), recentWordSessionsLimit)] {
+		detail.RecentSessions = append(detail.RecentSessions, m.studySession(session, now))
+	}
+
+	return detail
+}
+
+// GetWordReviews retrieves every review of a word, oldest first, with the
+// session, activity and group it was made in
+func (m *MemoryStore) GetWordReviews(wordID int64, options validation.ListOptions) ([]models.WordReviewHistoryItem, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.words[wordID]; !ok {
+		return nil, models.PageInfo{}, fmt.Errorf("%w: %d", ErrWordNotFound, wordID)
+	}
+
+	list := memoryList[models.WordReviewHistoryItem]{defaultSort: "created_at"}
+	for _, id := range sortedIDs(m.reviews) {
+		review := m.reviews[id]
+		if review.WordID != wordID {
+			continue
+		}
+		session := m.sessions[review.StudySessionID]
+		item := models.WordReviewHistoryItem{
+			WordReviewItem:    *review,
+			SessionStatus:     session.Status,
+			GroupID:           session.GroupID,
+			GroupName:         m.groups[session.GroupID].Name,
+			StudyActivityID:   session.StudyActivityID,
+			StudyActivityName: m.activities[session.StudyActivityID].Name,
+		}
+		list.rows = append(list.rows, memoryRow[models.WordReviewHistoryItem]{
+			item: item,
+			id:   review.ID,
+			fields: map[string]any{
+				"id":                review.ID,
+				"study_session_id":  review.StudySessionID,
+				"correct":           review.Correct,
+				"response_ms":       review.ResponseMS,
+				"hint_used":         review.HintUsed,
+				"grade":             review.Grade,
+				"created_at":        review.CreatedAt,
+				"group_id":          session.GroupID,
+				"study_activity_id": session.StudyActivityID,
+			},
+		})
+	}
+
+	reviews, info := list.page(options)
+	return reviews, info, nil
+}
+
+// CreateWord creates a new word and attaches it to the given groups
+func (m *MemoryStore) CreateWord(word models.Word, groupIDs []int64) (*models.WordDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGroups(groupIDs); err != nil {
+		return nil, err
+	}
+
+	word.ID = m.nextID("words")
+	m.words[word.ID] = &word
+	m.addWordToGroups(word.ID, groupIDs)
+
+	return m.wordDetail(word.ID), nil
+}
+
+// UpdateWord applies a partial update to a word. It returns nil if the word does not exist.
+func (m *MemoryStore) UpdateWord(id int64, update models.WordUpdate) (*models.WordDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	word, ok := m.words[id]
+	if !ok {
+		return nil, nil
+	}
+	if update.GroupIDs != nil {
+		if err := m.checkGroups(*update.GroupIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	setIfPresent(&word.Japanese, update.Japanese)
+	setIfPresent(&word.Romaji, update.Romaji)
+	setIfPresent(&word.English, update.English)
+	setIfPresent(&word.Parts, update.Parts)
+
+	// Replace the word's groups only when a new list was provided
+	if update.GroupIDs != nil {
+		for _, members := range m.groupWords {
+			delete(members, id)
+		}
+		m.addWordToGroups(id, *update.GroupIDs)
+	}
+
+	return m.wordDetail(id), nil
+}
+
+// setIfPresent overwrites a field with an optional update value
+func setIfPresent[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// DeleteWord deletes a word together with its group memberships and review history.
+// It returns false if the word does not exist.
+func (m *MemoryStore) DeleteWord(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.words[id]; !ok {
+		return false, nil
+	}
+
+	for _, members := range m.groupWords {
+		delete(members, id)
+	}
+	for reviewID, review := range m.reviews {
+		if review.WordID == id {
+			delete(m.reviews, reviewID)
+			delete(m.reviewSources, reviewID)
+		}
+	}
+	delete(m.schedules, id)
+	delete(m.words, id)
+
+	return true, nil
+}
+
+// ImportWords imports each batch of words into its group. A batch without a
+// group ID goes into the group with its group name, which is created if there
+// is none. Words are matched on their Japanese and English: existing words get
+// the imported romaji and parts instead of being added again. An imported
+// review schedule replaces the word's schedule only if it was reviewed more
+// recently.
+func (m *MemoryStore) ImportWords(imports []models.GroupImport) ([]models.ImportResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Check every group first so that a failed import changes nothing
+	for _, batch := range imports {
+		if batch.GroupID > 0 {
+			if err := m.checkGroups([]int64{batch.GroupID}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results := make([]models.ImportResult, len(imports))
+	for i, batch := range imports {
+		result := &results[i]
+		groupID := batch.GroupID
+		if groupID == 0 {
+			for _, id := range sortedIDs(m.groups) {
+				if m.groups[id].Name == batch.GroupName {
+					groupID = id
+					break
+				}
+			}
+		}
+		if groupID == 0 {
+			groupID = m.nextID("groups")
+			m.groups[groupID] = &models.Group{ID: groupID, Name: batch.GroupName}
+			m.groupWords[groupID] = make(map[int64]bool)
+			result.GroupCreated = true
+		}
+
+		for _, word := range batch.Words {
+			var existing *models.Word
+			for _, candidate := range m.words {
+				if candidate.Japanese == word.Japanese && candidate.English == word.English &&
+					(existing == nil || candidate.ID < existing.ID) {
+					existing = candidate
+				}
+			}
+
+			switch {
+			case existing == nil:
+				existing = &word.Word
+				existing.ID = m.nextID("words")
+				m.words[existing.ID] = existing
+				result.Created++
+			case existing.Romaji != word.Romaji || existing.Parts != word.Parts:
+				existing.Romaji, existing.Parts = word.Romaji, word.Parts
+				result.Updated++
+			default:
+				result.Unchanged++
+			}
+
+			if !m.groupWords[groupID][existing.ID] {
+				m.groupWords[groupID][existing.ID] = true
+				result.AddedToGroup++
+			}
+
+			if word.Schedule != nil {
+				current, ok := m.schedules[existing.ID]
+				if !ok || word.Schedule.LastReviewedAt.Truncate(time.Second).After(current.LastReviewedAt) {
+					schedule := *word.Schedule
+					schedule.WordID = existing.ID
+					schedule.DueAt = schedule.DueAt.UTC().Truncate(time.Second)
+					schedule.LastReviewedAt = schedule.LastReviewedAt.UTC().Truncate(time.Second)
+					m.schedules[existing.ID] = schedule
+					result.SchedulesImported++
+				}
+			}
+		}
+
+		result.Group = m.group(groupID)
+	}
+
+	return results, nil
+}
+
+// ExportGroup retrieves a group with its words, their review schedules and all
+// their reviews in the order they were made. It returns nil if the group does
+// not exist.
+func (m *MemoryStore) ExportGroup(id int64) (*models.GroupExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[id]; !ok {
+		return nil, nil
+	}
+	export := &models.GroupExport{Group: m.group(id)}
+
+	for _, wordID := range sortedIDs(m.words) {
+		if !m.groupWords[id][wordID] {
+			continue
+		}
+		export.Words = append(export.Words, *m.words[wordID])
+		if schedule, ok := m.schedules[wordID]; ok {
+			export.Schedules = append(export.Schedules, schedule)
+		}
+	}
+
+	for _, reviewID := range sortedIDs(m.reviews) {
+		if review := m.reviews[reviewID]; m.groupWords[id][review.WordID] {
+			export.Reviews = append(export.Reviews, *review)
+		}
+	}
+	slices.SortStableFunc(export.Reviews, func(a, b models.WordReviewItem) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return export, nil
+}
+
+// ExportBackup archives all words, groups, study activities and study history
+func (m *MemoryStore) ExportBackup() (*models.Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backup := newBackup()
+	for _, id := range sortedIDs(m.words) {
+		backup.Words = append(backup.Words, *m.words[id])
+	}
+	for _, groupID := range sortedIDs(m.groups) {
+		backup.Groups = append(backup.Groups, models.BackupGroup{ID: groupID, Name: m.groups[groupID].Name})
+		for _, wordID := range sortedIDs(m.groupWords[groupID]) {
+			backup.GroupWords = append(backup.GroupWords, models.BackupGroupWord{GroupID: groupID, WordID: wordID})
+		}
+	}
+	for _, id := range sortedIDs(m.activities) {
+		backup.StudyActivities = append(backup.StudyActivities, *m.activities[id])
+	}
+	for _, id := range sortedIDs(m.sessions) {
+		session := m.sessions[id]
+		backup.StudySessions = append(backup.StudySessions, models.BackupStudySession{
+			ID:              session.ID,
+			GroupID:         session.GroupID,
+			StudyActivityID: session.StudyActivityID,
+			Status:          session.Status,
+			CreatedAt:       session.CreatedAt,
+			EndedAt:         session.EndedAt,
+		})
+	}
+	for _, id := range sortedIDs(m.reviews) {
+		backup.Reviews = append(backup.Reviews, *m.reviews[id])
+	}
+	for _, wordID := range sortedIDs(m.schedules) {
+		backup.Schedules = append(backup.Schedules, m.schedules[wordID])
+	}
+
+	return backup, nil
+}
+
+// RestoreBackup adds the records of a backup to the store with new IDs,
+// keeping the vocabulary that already exists and the study history restored
+// from the same archive before, see DBService.RestoreBackup
+func (m *MemoryStore) RestoreBackup(backup *models.Backup) (*models.RestoreResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &models.RestoreResult{}
+
+	// restore returns the lowest ID of items matching an archived record,
+	// or else the ID of a new item made by create
+	restore := func(counts *models.RestoreCounts, ids []int64, match func(id int64) bool, create func() int64) int64 {
+		if i := slices.IndexFunc(ids, match); i >= 0 {
+			counts.Existing++
+			return ids[i]
+		}
+		counts.Created++
+		return create()
+	}
+
+	words := make(map[int64]int64, len(backup.Words))
+	for _, word := range backup.Words {
+		words[word.ID] = restore(&result.Words, sortedIDs(m.words), func(id int64) bool {
+			return m.words[id].Japanese == word.Japanese && m.words[id].English == word.English
+		}, func() int64 {
+			restored := word
+			restored.ID = m.nextID("words")
+			m.words[restored.ID] = &restored
+			return restored.ID
+		})
+	}
+
+	groups := make(map[int64]int64, len(backup.Groups))
+	for _, group := range backup.Groups {
+		groups[group.ID] = restore(&result.Groups, sortedIDs(m.groups), func(id int64) bool {
+			return m.groups[id].Name == group.Name
+		}, func() int64 {
+			id := m.nextID("groups")
+			m.groups[id] = &models.Group{ID: id, Name: group.Name}
+			m.groupWords[id] = make(map[int64]bool)
+			return id
+		})
+	}
+
+	for _, groupWord := range backup.GroupWords {
+		groupID, wordID := groups[groupWord.GroupID], words[groupWord.WordID]
+		if m.groupWords[groupID][wordID] {
+			result.GroupWords.Existing++
+			continue
+		}
+		m.groupWords[groupID][wordID] = true
+		result.GroupWords.Created++
+	}
+
+	activities := make(map[int64]int64, len(backup.StudyActivities))
+	for _, activity := range backup.StudyActivities {
+		activities[activity.ID] = restore(&result.StudyActivities, sortedIDs(m.activities), func(id int64) bool {
+			return m.activities[id].Name == activity.Name
+		}, func() int64 {
+			restored := activity
+			restored.ID = m.nextID("study_activities")
+			restored.CreatedAt = restored.CreatedAt.UTC().Truncate(time.Second)
+			m.activities[restored.ID] = &restored
+			return restored.ID
+		})
+	}
+
+	sessions := make(map[int64]int64, len(backup.StudySessions))
+	for _, session := range backup.StudySessions {
+		groupID, activityID := groups[session.GroupID], activities[session.StudyActivityID]
+		createdAt := session.CreatedAt.UTC().Truncate(time.Second)
+		from := restoredFrom(backup, session.ID)
+		sessions[session.ID] = restore(&result.StudySessions, sortedIDs(m.sessions), func(id int64) bool {
+			return m.sessions[id].RestoredFrom == from
+		}, func() int64 {
+			restored := &memorySession{
+				ID:              m.nextID("study_sessions"),
+				GroupID:         groupID,
+				StudyActivityID: activityID,
+				Status:          session.Status,
+				CreatedAt:       createdAt,
+				RestoredFrom:    from,
+			}
+			if session.EndedAt != nil {
+				endedAt := session.EndedAt.UTC().Truncate(time.Second)
+				restored.EndedAt = &endedAt
+			}
+			m.sessions[restored.ID] = restored
+			return restored.ID
+		})
+	}
+
+	for _, review := range backup.Reviews {
+		wordID, sessionID := words[review.WordID], sessions[review.StudySessionID]
+		createdAt := review.CreatedAt.UTC().Truncate(time.Second)
+		from := restoredFrom(backup, review.ID)
+		restore(&result.Reviews, sortedIDs(m.reviews), func(id int64) bool {
+			return m.reviewSources[id] == from
+		}, func() int64 {
+			restored := review
+			restored.ID = m.nextID("word_review_items")
+			restored.WordID, restored.StudySessionID, restored.CreatedAt = wordID, sessionID, createdAt
+			m.reviews[restored.ID] = &restored
+			m.reviewSources[restored.ID] = from
+			return restored.ID
+		})
+	}
+
+	for _, schedule := range backup.Schedules {
+		wordID := words[schedule.WordID]
+		current, ok := m.schedules[wordID]
+		if ok && !schedule.LastReviewedAt.Truncate(time.Second).After(current.LastReviewedAt) {
+			result.Schedules.Existing++
+			continue
+		}
+		schedule.WordID = wordID
+		schedule.DueAt = schedule.DueAt.UTC().Truncate(time.Second)
+		schedule.LastReviewedAt = schedule.LastReviewedAt.UTC().Truncate(time.Second)
+		m.schedules[wordID] = schedule
+		result.Schedules.Created++
+	}
+
+	return result, nil
+}
+
+// checkGroups verifies that every group exists
+func (m *MemoryStore) checkGroups(groupIDs []int64) error {
+	for _, groupID := range groupIDs {
+		if _, ok := m.groups[groupID]; !ok {
+			return fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+		}
+	}
+	return nil
+}
+
+// addWordToGroups attaches a word to each of the given groups
+func (m *MemoryStore) addWordToGroups(wordID int64, groupIDs []int64) {
+	for _, groupID := range groupIDs {
+		m.groupWords[groupID][wordID] = true
+	}
+}
+
+// group returns a group with its word count
+func (m *MemoryStore) group(id int64) models.Group {
+	group := *m.groups[id]
+	group.WordCount = len(m.groupWords[id])
+	return group
+}
+
+// GetGroups retrieves a paginated list of groups
+func (m *MemoryStore) GetGroups(options validation.ListOptions) ([]models.Group, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := memoryList[models.Group]{defaultSort: "id"}
+	for _, id := range sortedIDs(m.groups) {
+		group := m.group(id)
+		list.rows = append(list.rows, memoryRow[models.Group]{
+			item: group,
+			id:   id,
+			fields: map[string]any{
+				"id":         id,
+				"name":       group.Name,
+				"word_count": group.WordCount,
+			},
+		})
+	}
+
+	groups, info := list.page(options)
+	return groups, info, nil
+}
+
+// GetGroup retrieves a single group by ID with statistics
+func (m *MemoryStore) GetGroup(id int64) (*models.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[id]; !ok {
+		return nil, nil
+	}
+	group := m.group(id)
+	return &group, nil
+}
+
+// GetGroupWords retrieves words for a specific group
+func (m *MemoryStore) GetGroupWords(groupID int64, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	words, info := m.wordList(sortedIDs(m.groupWords[groupID])).page(options)
+	return words, info, nil
+}
+
+// CreateGroup creates a new, empty group
+func (m *MemoryStore) CreateGroup(name string) (*models.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group := &models.Group{ID: m.nextID("groups"), Name: name}
+	m.groups[group.ID] = group
+	m.groupWords[group.ID] = make(map[int64]bool)
+
+	created := *group
+	return &created, nil
+}
+
+// RenameGroup changes the name of a group. It returns nil if the group does not exist.
+func (m *MemoryStore) RenameGroup(id int64, name string) (*models.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[id]
+	if !ok {
+		return nil, nil
+	}
+	group.Name = name
+
+	renamed := m.group(id)
+	return &renamed, nil
+}
+
+// DeleteGroup deletes a group, its word memberships and its study sessions.
+// The words themselves are kept. It returns false if the group does not exist.
+func (m *MemoryStore) DeleteGroup(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.groups[id]; !ok {
+		return false, nil
+	}
+
+	for sessionID, session := range m.sessions {
+		if session.GroupID == id {
+			m.deleteSession(sessionID)
+		}
+	}
+	delete(m.groupWords, id)
+	delete(m.groups, id)
+
+	return true, nil
+}
+
+// deleteSession deletes a study session with its reviews and tokens
+func (m *MemoryStore) deleteSession(id int64) {
+	for reviewID, review := range m.reviews {
+		if review.StudySessionID == id {
+			delete(m.reviews, reviewID)
+			delete(m.reviewSources, reviewID)
+		}
+	}
+	for token, t := range m.tokens {
+		if t.SessionID == id {
+			delete(m.tokens, token)
+		}
+	}
+	delete(m.sessions, id)
+}
+
+// AddWordsToGroup adds words to a group, ignoring words that are already members.
+// It returns the number of newly added words.
+func (m *MemoryStore) AddWordsToGroup(groupID int64, wordIDs []int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.groupWords[groupID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrGroupNotFound, groupID)
+	}
+	for _, wordID := range wordIDs {
+		if _, ok := m.words[wordID]; !ok {
+			return 0, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrWordNotFound, wordID)
+		}
+	}
+
+	added := 0
+	for _, wordID := range wordIDs {
+		if !members[wordID] {
+			members[wordID] = true
+			added++
+		}
+	}
+	return added, nil
+}
+
+// RemoveWordsFromGroup removes words from a group and returns the number of removed words
+func (m *MemoryStore) RemoveWordsFromGroup(groupID int64, wordIDs []int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.groupWords[groupID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrGroupNotFound, groupID)
+	}
+
+	removed := 0
+	for _, wordID := range wordIDs {
+		if members[wordID] {
+			delete(members, wordID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// studySession builds the API view of a stored session
+func (m *MemoryStore) studySession(stored *memorySession, now time.Time) models.StudySession {
+	session := models.StudySession{
+		ID:              stored.ID,
+		GroupID:         stored.GroupID,
+		CreatedAt:       stored.CreatedAt,
+		StudyActivityID: stored.StudyActivityID,
+		GroupName:       m.groups[stored.GroupID].Name,
+		Status:          stored.Status,
+	}
+	for _, review := range m.reviews {
+		if review.StudySessionID == stored.ID {
+			session.ReviewItemsCount++
+		}
+	}
+
+	var endedAt sql.NullTime
+	if stored.EndedAt != nil {
+		endedAt = sql.NullTime{Time: *stored.EndedAt, Valid: true}
+	}
+	setSessionTimes(&session, endedAt, now)
+	return session
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// memoryRow is a list item together with the values of its whitelisted fields,
+// mirroring the output columns of a listQuery. Values are nil, numbers, bools,
+// strings or times.
+type memoryRow[T any] struct {
+	item   T
+	id     int64
+	fields map[string]any
+}
+
+// memoryList sorts, filters and pages rows the same way queryList does in SQL
+type memoryList[T any] struct {
+	rows        []memoryRow[T]
+	defaultSort string
+	defaultDesc bool
+}
+
+// page returns one page of the list as described by options
+func (l memoryList[T]) page(options validation.ListOptions) ([]T, models.PageInfo) {
+	info := models.PageInfo{Page: options.Page, PageSize: options.PageSize, CursorMode: options.CursorMode}
+	sortBy, desc := options.SortBy, options.Desc
+	if sortBy == "" {
+		sortBy, desc = l.defaultSort, l.defaultDesc
+	}
+
+	// order compares two rows by the sort field and then by ID
+	order := func(value any, id int64, otherValue any, otherID int64) int {
+		c := compareValues(value, otherValue)
+		if c == 0 {
+			c = cmp.Compare(id, otherID)
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+
+	var rows []memoryRow[T]
+	for _, row := range l.rows {
+		if !matchesFilters(row.fields, options.Filters) {
+			continue
+		}
+		if after := options.After; after != nil && order(row.fields[sortBy], row.id, after.Value, after.ID) <= 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return order(rows[i].fields[sortBy], rows[i].id, rows[j].fields[sortBy], rows[j].id) < 0
+	})
+
+	offset := 0
+	if options.CursorMode {
+		info.Page = 0
+	} else {
+		info.TotalItems = len(rows)
+		offset = min((options.Page-1)*options.PageSize, len(rows))
+	}
+	more := len(rows) > offset+options.PageSize
+	rows = rows[offset:min(offset+options.PageSize, len(rows))]
+
+	// A page in cursor mode continues after its last row if more rows follow
+	if options.CursorMode && more && len(rows) > 0 {
+		last := rows[len(rows)-1]
+		value := last.fields[sortBy]
+		if t, ok := normalizeValue(value).(time.Time); ok {
+			value = sqliteTimestamp(t)
+		}
+		cursor := validation.Cursor{SortBy: options.SortBy, Desc: options.Desc, Value: value, ID: last.id}
+		info.NextCursor = cursor.Encode()
+	}
+
+	items := make([]T, len(rows))
+	for i, row := range rows {
+		items[i] = row.item
+	}
+	return items, info
+}
+
+// matchesFilters reports whether a row passes every filter. As in SQL,
+// comparisons with NULL never match.
+func matchesFilters(fields map[string]any, filters []validation.Filter) bool {
+	for _, filter := range filters {
+		value := normalizeValue(fields[filter.Field])
+		other := filter.Value
+		if filter.ValueField != "" {
+			other = fields[filter.ValueField]
+		}
+		other = normalizeValue(other)
+		if value == nil || other == nil {
+			return false
+		}
+
+		if filter.Op == validation.OpContains {
+			text, _ := value.(string)
+			if !strings.Contains(strings.ToLower(text), strings.ToLower(other.(string))) {
+				return false
+			}
+			continue
+		}
+
+		c := compareValues(value, other)
+		var ok bool
+		switch filter.Op {
+		case validation.OpEq:
+			ok = c == 0
+		case validation.OpNe:
+			ok = c != 0
+		case validation.OpGt:
+			ok = c > 0
+		case validation.OpGte:
+			ok = c >= 0
+		case validation.OpLt:
+			ok = c < 0
+		case validation.OpLte:
+			ok = c <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeValue converts a field value to nil, float64, string or a UTC time
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		if v {
+			return float64(1)
+		}
+		return float64(0)
+	case *int:
+		if v == nil {
+			return nil
+		}
+		return float64(*v)
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC()
+	case string:
+		return v
+	default:
+		return nil
+	}
+}
+
+// compareValues orders values like SQLite: NULLs first, then numbers, then
+// text. Times compare with each other and with timestamps written as text.
+func compareValues(a, b any) int {
+	a, b = normalizeValue(a), normalizeValue(b)
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := parseStoredTime(b); ok {
+			return ta.Compare(tb)
+		}
+	}
+	if tb, ok := b.(time.Time); ok {
+		if ta, ok := parseStoredTime(a); ok {
+			return ta.Compare(tb)
+		}
+	}
+
+	if c := cmp.Compare(valueRank(a), valueRank(b)); c != 0 {
+		return c
+	}
+	switch x := a.(type) {
+	case float64:
+		return cmp.Compare(x, b.(float64))
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
+
+// valueRank is the position of a value's type in SQLite's sort order
+func valueRank(value any) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case float64:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseStoredTime reads a time, or a timestamp as stored by SQLite or carried in a cursor
+func parseStoredTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
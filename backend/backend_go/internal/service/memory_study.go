@@ -0,0 +1,480 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// sessionList lists the stored sessions that pass keep
+func (m *MemoryStore) sessionList(keep func(*memorySession) bool) memoryList[models.StudySession] {
+	now := time.Now()
+	list := memoryList[models.StudySession]{defaultSort: "created_at", defaultDesc: true}
+	for _, id := range sortedIDs(m.sessions) {
+		stored := m.sessions[id]
+		if !keep(stored) {
+			continue
+		}
+		session := m.studySession(stored, now)
+		list.rows = append(list.rows, memoryRow[models.StudySession]{
+			item: session,
+			id:   id,
+			fields: map[string]any{
+				"id":                 id,
+				"group_id":           session.GroupID,
+				"group_name":         session.GroupName,
+				"study_activity_id":  session.StudyActivityID,
+				"activity_name":      m.activities[session.StudyActivityID].Name,
+				"status":             session.Status,
+				"created_at":         session.CreatedAt,
+				"ended_at":           stored.EndedAt,
+				"review_items_count": session.ReviewItemsCount,
+			},
+		})
+	}
+	return list
+}
+
+// GetStudySessions retrieves a paginated list of study sessions
+func (m *MemoryStore) GetStudySessions(options validation.ListOptions) ([]models.StudySession, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions, info := m.sessionList(func(*memorySession) bool { return true }).page(options)
+	return sessions, info, nil
+}
+
+// GetStudySession retrieves a single study session by ID
+func (m *MemoryStore) GetStudySession(id int64) (*models.StudySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	session := m.studySession(stored, time.Now())
+	return &session, nil
+}
+
+// GetStudySessionWords retrieves words reviewed in a study session, in the order
+// they were first reviewed, together with every attempt made in the session
+func (m *MemoryStore) GetStudySessionWords(sessionID int64, options validation.ListOptions) ([]models.SessionWord, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Reviews of each word in the session, in the order they were made
+	firstReviewIDs := make(map[int64]int64)
+	sessionReviews := make(map[int64][]models.WordReviewItem)
+	for _, id := range sortedIDs(m.reviews) {
+		review := m.reviews[id]
+		if review.StudySessionID != sessionID {
+			continue
+		}
+		if _, ok := firstReviewIDs[review.WordID]; !ok {
+			firstReviewIDs[review.WordID] = id
+		}
+		sessionReviews[review.WordID] = append(sessionReviews[review.WordID], *review)
+	}
+
+	stats := m.wordStats()
+	list := memoryList[models.SessionWord]{defaultSort: "first_review_id"}
+	for _, wordID := range sortedIDs(firstReviewIDs) {
+		reviews := sessionReviews[wordID]
+		sort.SliceStable(reviews, func(i, j int) bool {
+			return reviews[i].CreatedAt.Before(reviews[j].CreatedAt)
+		})
+		word := models.SessionWord{
+			WordWithStats: wordWithStats(m.words[wordID], stats[wordID]),
+			Reviews:       reviews,
+		}
+		row := wordRow(word, word.WordWithStats, stats[wordID])
+		row.fields["first_review_id"] = firstReviewIDs[wordID]
+		list.rows = append(list.rows, row)
+	}
+
+	words, info := list.page(options)
+	return words, info, nil
+}
+
+// CreateStudySession creates a new study session for an existing group and study activity
+func (m *MemoryStore) CreateStudySession(groupID, studyActivityID int64) (*models.StudySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createStudySession(groupID, studyActivityID)
+}
+
+// createStudySession implements CreateStudySession with the lock held
+func (m *MemoryStore) createStudySession(groupID, studyActivityID int64) (*models.StudySession, error) {
+	if _, ok := m.groups[groupID]; !ok {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrGroupNotFound, groupID)
+	}
+	if _, ok := m.activities[studyActivityID]; !ok {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidReference, ErrActivityNotFound, studyActivityID)
+	}
+
+	stored := &memorySession{
+		ID:              m.nextID("study_sessions"),
+		GroupID:         groupID,
+		StudyActivityID: studyActivityID,
+		Status:          models.SessionStatusActive,
+		CreatedAt:       memoryNow(),
+	}
+	m.sessions[stored.ID] = stored
+
+	// Like the SQLite store, the new session is returned without its group name
+	session := m.studySession(stored, time.Now())
+	session.GroupName = ""
+	return &session, nil
+}
+
+// FinishStudySession marks an active study session as finished. It returns nil
+// if the session does not exist and ErrSessionNotActive if it was already closed.
+func (m *MemoryStore) FinishStudySession(id int64) (*models.StudySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if stored.Status != models.SessionStatusActive {
+		return nil, ErrSessionNotActive
+	}
+
+	endedAt := memoryNow()
+	stored.Status = models.SessionStatusFinished
+	stored.EndedAt = &endedAt
+
+	session := m.studySession(stored, time.Now())
+	return &session, nil
+}
+
+// ExpireStaleSessions marks active study sessions without any review in the
+// given timeout as abandoned, ending them at their last review. It returns the
+// number of expired sessions.
+func (m *MemoryStore) ExpireStaleSessions(timeout time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lastActivity := make(map[int64]time.Time)
+	for _, session := range m.sessions {
+		lastActivity[session.ID] = session.CreatedAt
+	}
+	for _, review := range m.reviews {
+		if review.CreatedAt.After(lastActivity[review.StudySessionID]) {
+			lastActivity[review.StudySessionID] = review.CreatedAt
+		}
+	}
+
+	cutoff := time.Now().Add(-timeout)
+	var expired int64
+	for _, session := range m.sessions {
+		last := lastActivity[session.ID]
+		if session.Status == models.SessionStatusActive && last.Before(cutoff) {
+			session.Status = models.SessionStatusAbandoned
+			session.EndedAt = &last
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// activityRow makes a study activity list row
+func activityRow(activity models.StudyActivity) memoryRow[models.StudyActivity] {
+	return memoryRow[models.StudyActivity]{
+		item: activity,
+		id:   activity.ID,
+		fields: map[string]any{
+			"id":         activity.ID,
+			"name":       activity.Name,
+			"enabled":    activity.Enabled,
+			"created_at": activity.CreatedAt,
+		},
+	}
+}
+
+// GetStudyActivities retrieves a paginated list of study activities, optionally only enabled ones
+func (m *MemoryStore) GetStudyActivities(enabledOnly bool, options validation.ListOptions) ([]models.StudyActivity, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := memoryList[models.StudyActivity]{defaultSort: "id"}
+	for _, id := range sortedIDs(m.activities) {
+		activity := m.activities[id]
+		if enabledOnly && !activity.Enabled {
+			continue
+		}
+		list.rows = append(list.rows, activityRow(*activity))
+	}
+
+	activities, info := list.page(options)
+	return activities, info, nil
+}
+
+// GetStudyActivity retrieves a study activity by ID
+func (m *MemoryStore) GetStudyActivity(id int64) (*models.StudyActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activity, ok := m.activities[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *activity
+	return &found, nil
+}
+
+// GetStudyActivitySessions retrieves study sessions for a specific activity
+func (m *MemoryStore) GetStudyActivitySessions(activityID int64, options validation.ListOptions) ([]models.StudySession, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := m.sessionList(func(session *memorySession) bool {
+		return session.StudyActivityID == activityID
+	})
+	sessions, info := list.page(options)
+	return sessions, info, nil
+}
+
+// CreateStudyActivity adds a new activity to the catalog
+func (m *MemoryStore) CreateStudyActivity(activity models.StudyActivity) (*models.StudyActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activity.ID = m.nextID("study_activities")
+	activity.CreatedAt = memoryNow()
+	m.activities[activity.ID] = &activity
+
+	created := activity
+	return &created, nil
+}
+
+// UpdateStudyActivity applies a partial update to a study activity.
+// It returns nil if the activity does not exist.
+func (m *MemoryStore) UpdateStudyActivity(id int64, update models.StudyActivityUpdate) (*models.StudyActivity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	activity, ok := m.activities[id]
+	if !ok {
+		return nil, nil
+	}
+
+	setIfPresent(&activity.Name, update.Name)
+	setIfPresent(&activity.Description, update.Description)
+	setIfPresent(&activity.ThumbnailURL, update.ThumbnailURL)
+	setIfPresent(&activity.LaunchURL, update.LaunchURL)
+	setIfPresent(&activity.Enabled, update.Enabled)
+
+	updated := *activity
+	return &updated, nil
+}
+
+// DeleteStudyActivity removes an activity from the catalog. Activities that
+// already have study sessions cannot be deleted and should be disabled instead.
+// It returns false if the activity does not exist.
+func (m *MemoryStore) DeleteStudyActivity(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.sessions {
+		if session.StudyActivityID == id {
+			return false, ErrActivityInUse
+		}
+	}
+	if _, ok := m.activities[id]; !ok {
+		return false, nil
+	}
+
+	delete(m.activities, id)
+	return true, nil
+}
+
+// LaunchStudyActivity starts a study session for the given activity and group
+// and mints a session token for the external study app
+func (m *MemoryStore) LaunchStudyActivity(activityID, groupID int64) (*models.StudyLaunch, error) {
+	return launchStudyActivity(m, activityID, groupID)
+}
+
+// createLaunchedSession creates a study session together with its session token
+func (m *MemoryStore) createLaunchedSession(groupID, studyActivityID int64, token string, expiresAt time.Time) (*models.StudySession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, err := m.createStudySession(groupID, studyActivityID)
+	if err != nil {
+		return nil, err
+	}
+	m.tokens[token] = memoryToken{SessionID: session.ID, ExpiresAt: expiresAt}
+	return session, nil
+}
+
+// ValidateSessionToken checks the token sent for a study session, see
+// DBService.ValidateSessionToken
+func (m *MemoryStore) ValidateSessionToken(sessionID int64, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	launched := false
+	for _, t := range m.tokens {
+		if t.SessionID == sessionID {
+			launched = true
+			break
+		}
+	}
+	t, ok := m.tokens[token]
+	valid := ok && t.SessionID == sessionID && t.ExpiresAt.After(time.Now())
+	return checkSessionToken(token, launched, valid)
+}
+
+// AddWordReview adds a new word review record and reschedules the word.
+// The review is recorded at the current time. Sessions that have ended return
+// ErrSessionNotActive.
+func (m *MemoryStore) AddWordReview(review models.WordReviewItem) (*models.WordReviewItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.checkReviewedSession(review.StudySessionID); err != nil {
+		return nil, err
+	}
+	if err := m.checkReviewedWord(review.StudySessionID, review.WordID); err != nil {
+		return nil, err
+	}
+
+	review.CreatedAt = memoryNow()
+	review.ID = m.insertWordReview(review)
+	return &review, nil
+}
+
+// AddWordReviews records a batch of reviews for a study session. Reviews are
+// applied in the order they were answered, and reviews of words that do not
+// exist or are not in the session's group are reported as failed without
+// aborting the batch. Sessions that have ended return ErrSessionNotActive and
+// answer times outside the session ErrAnswerTimeOutOfRange.
+// The results are returned in the order of the given reviews.
+func (m *MemoryStore) AddWordReviews(studySessionID int64, reviews []models.WordReviewItem) ([]models.ReviewResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	startedAt, err := m.checkReviewedSession(studySessionID)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err = checkAnswerTimes(reviews, startedAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	order := reviewOrder(reviews)
+
+	results := make([]models.ReviewResult, len(reviews))
+	for _, i := range order {
+		review := reviews[i]
+		review.StudySessionID = studySessionID
+		results[i] = models.ReviewResult{Index: i, WordID: review.WordID}
+
+		err := m.checkReviewedWord(studySessionID, review.WordID)
+		if errors.Is(err, ErrWordNotFound) || errors.Is(err, ErrWordNotInGroup) {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		review.CreatedAt = review.CreatedAt.UTC().Truncate(time.Second)
+		results[i].Success = true
+		results[i].ReviewID = m.insertWordReview(review)
+	}
+
+	return results, nil
+}
+
+// checkReviewedSession verifies that a study session exists and is still
+// active and returns the time it started
+func (m *MemoryStore) checkReviewedSession(studySessionID int64) (time.Time, error) {
+	session, ok := m.sessions[studySessionID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %d", ErrSessionNotFound, studySessionID)
+	}
+	if session.Status != models.SessionStatusActive {
+		return time.Time{}, ErrSessionNotActive
+	}
+	return session.CreatedAt, nil
+}
+
+// checkReviewedWord verifies that a word exists and belongs to the group being
+// studied in the session
+func (m *MemoryStore) checkReviewedWord(studySessionID, wordID int64) error {
+	if _, ok := m.words[wordID]; !ok {
+		return fmt.Errorf("%w: %d", ErrWordNotFound, wordID)
+	}
+	if !m.groupWords[m.sessions[studySessionID].GroupID][wordID] {
+		return fmt.Errorf("%w: %w: word %d", ErrInvalidReference, ErrWordNotInGroup, wordID)
+	}
+	return nil
+}
+
+// insertWordReview stores a review at its CreatedAt time and reschedules the word
+func (m *MemoryStore) insertWordReview(review models.WordReviewItem) int64 {
+	review.ID = m.nextID("word_review_items")
+	m.reviews[review.ID] = &review
+
+	var prev *models.ReviewSchedule
+	if schedule, ok := m.schedules[review.WordID]; ok {
+		prev = &schedule
+	}
+	next := NextReviewSchedule(review.WordID, prev, reviewQuality(review), review.CreatedAt.UTC())
+	next.DueAt = next.DueAt.Truncate(time.Second)
+	next.LastReviewedAt = next.LastReviewedAt.Truncate(time.Second)
+	m.schedules[review.WordID] = next
+
+	return review.ID
+}
+
+// GetDueWords retrieves words that are due for review, optionally limited to a group.
+// Words that were never reviewed are always due and are returned after overdue ones.
+func (m *MemoryStore) GetDueWords(groupID int64, options validation.ListOptions) ([]models.DueWord, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := memoryNow()
+	neverReviewed := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	// Most overdue first by default
+	list := memoryList[models.DueWord]{defaultSort: "due_order"}
+	for _, id := range sortedIDs(m.words) {
+		if groupID != 0 && !m.groupWords[groupID][id] {
+			continue
+		}
+
+		word := models.DueWord{Word: *m.words[id]}
+		dueOrder := neverReviewed
+		if schedule, ok := m.schedules[id]; ok {
+			if schedule.DueAt.After(now) {
+				continue
+			}
+			word.Schedule = &schedule
+			dueOrder = schedule.DueAt
+		}
+
+		list.rows = append(list.rows, memoryRow[models.DueWord]{
+			item: word,
+			id:   id,
+			fields: map[string]any{
+				"id":        id,
+				"japanese":  word.Japanese,
+				"romaji":    word.Romaji,
+				"english":   word.English,
+				"due_order": dueOrder,
+			},
+		})
+	}
+
+	words, info := list.page(options)
+	return words, info, nil
+}
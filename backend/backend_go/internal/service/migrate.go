@@ -0,0 +1,518 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/db"
+)
+
+// SQLExecutor is implemented by both *DB and *Tx so that migrations and
+// seeds can run either standalone or inside a larger transaction
+type SQLExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Dialect() Dialect
+}
+
+// migration is an embedded migration file. Its version is the file name, e.g.
+// 0001_init.sql, and its down migration is the matching .down.sql file.
+type migration struct {
+	version  string
+	up       string
+	down     string
+	hasDown  bool
+	checksum string
+}
+
+// MigrationStatus describes a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   string
+	AppliedAt *time.Time
+	// Modified is set when the file no longer matches the checksum recorded when it was applied
+	Modified bool
+	// Missing is set when an applied migration has no file in this build
+	Missing bool
+	// Unavailable names the SQLite module the migration needs that this build
+	// lacks, see migrationModules
+	Unavailable string
+}
+
+// migrationModules names the SQLite migrations that need a module which
+// mattn/go-sqlite3 only compiles in with a build tag, sqlite_<module>. Builds
+// without the module skip these migrations and fall back, e.g. word search to
+// LIKE matching without fts5.
+var migrationModules = map[string]string{
+	"0009_words_fts.sql": "fts5",
+}
+
+// unavailableModule returns the SQLite module that a migration needs and this
+// build lacks, or "" if it can be applied
+func unavailableModule(conn SQLExecutor, version string) (string, error) {
+	module, ok := migrationModules[version]
+	if !ok || conn.Dialect() != SQLite {
+		return "", nil
+	}
+	available, err := sqliteHasModule(conn, module)
+	if err != nil || available {
+		return "", err
+	}
+	return module, nil
+}
+
+// loadMigrations reads the embedded migrations of a dialect in version order
+func loadMigrations(d Dialect) ([]migration, error) {
+	files, err := fs.Glob(db.Migrations, d.migrationsGlob())
+	if err != nil {
+		return nil, fmt.Errorf("error finding migration files: %v", err)
+	}
+
+	// Sort migration files by name
+	sort.Strings(files)
+
+	var migrations []migration
+	for _, file := range files {
+		if strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+
+		up, err := fs.ReadFile(db.Migrations, file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration file %s: %v", file, err)
+		}
+		sum := sha256.Sum256(up)
+		m := migration{version: path.Base(file), up: string(up), checksum: hex.EncodeToString(sum[:])}
+
+		down, err := fs.ReadFile(db.Migrations, strings.TrimSuffix(file, ".sql")+".down.sql")
+		if err == nil {
+			m.down, m.hasDown = string(down), true
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	return migrations, nil
+}
+
+// appliedMigration is a row of schema_migrations. Migrations applied before
+// checksums were recorded have an empty checksum.
+type appliedMigration struct {
+	appliedAt time.Time
+	checksum  string
+}
+
+// appliedMigrations reads schema_migrations, which does not exist yet in a new database
+func appliedMigrations(conn SQLExecutor) (map[string]appliedMigration, error) {
+	applied := make(map[string]appliedMigration)
+
+	var tables int
+	if err := conn.QueryRow(conn.Dialect().tableExistsQuery(), "schema_migrations").Scan(&tables); err != nil {
+		return nil, fmt.Errorf("error checking schema_migrations table: %v", err)
+	}
+	if tables == 0 {
+		return applied, nil
+	}
+
+	checksum := "checksum"
+	var columns int
+	err := conn.QueryRow(conn.Dialect().columnExistsQuery(), "schema_migrations", "checksum").Scan(&columns)
+	if err != nil {
+		return nil, fmt.Errorf("error checking schema_migrations table: %v", err)
+	}
+	if columns == 0 {
+		checksum = "NULL"
+	}
+
+	rows, err := conn.Query("SELECT version, applied_at, " + checksum + " FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		var appliedAt sql.NullTime
+		var sum sql.NullString
+		if err := rows.Scan(&version, &appliedAt, &sum); err != nil {
+			return nil, fmt.Errorf("error scanning applied migration: %v", err)
+		}
+		applied[version] = appliedMigration{appliedAt: appliedAt.Time, checksum: sum.String}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+
+	return applied, nil
+}
+
+// MigrationStatuses lists the migrations of the database's dialect followed by
+// any applied migrations that this build does not have
+func MigrationStatuses(conn SQLExecutor) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(conn.Dialect())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version}
+		if status.Unavailable, err = unavailableModule(conn, m.version); err != nil {
+			return nil, err
+		}
+		if a, ok := applied[m.version]; ok {
+			status.AppliedAt = &a.appliedAt
+			status.Modified = a.checksum != "" && a.checksum != m.checksum
+			delete(applied, m.version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	var missing []string
+	for version := range applied {
+		missing = append(missing, version)
+	}
+	sort.Strings(missing)
+	for _, version := range missing {
+		appliedAt := applied[version].appliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: &appliedAt, Missing: true})
+	}
+
+	return statuses, nil
+}
+
+// CheckSchema verifies that every migration has been applied unchanged and
+// that the database has no migrations this build does not know about.
+// Migrations that need a SQLite module this build lacks may only be pending.
+func CheckSchema(conn SQLExecutor) error {
+	statuses, err := MigrationStatuses(conn)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		switch {
+		case status.Missing:
+			return fmt.Errorf("database schema is newer than this build: unknown migration %s", status.Version)
+		case status.Modified:
+			return fmt.Errorf("migration %s was modified after it was applied", status.Version)
+		case status.Unavailable != "" && status.AppliedAt != nil:
+			return fmt.Errorf("migration %s needs SQLite's %s module, which this build lacks: build with -tags sqlite_%s",
+				status.Version, status.Unavailable, status.Unavailable)
+		case status.Unavailable != "":
+		case status.AppliedAt == nil:
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is out of date, %d pending migrations (%s), run mage migrate",
+			len(pending), strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// Migrate applies the embedded migrations of the database's dialect that have
+// not been applied yet in order, each in its own transaction, recording them in
+// schema_migrations and writing progress to out. It refuses to run if an
+// applied migration was modified since. Migrations that need a SQLite module
+// this build lacks are skipped, see migrationModules.
+func Migrate(conn SQLExecutor, out io.Writer) error {
+	if err := ensureMigrationsTable(conn); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(conn.Dialect())
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		a, ok := applied[m.version]
+		if !ok {
+			continue
+		}
+		switch a.checksum {
+		case m.checksum:
+		case "":
+			// Migrations applied before checksums were recorded are trusted as they are
+			if _, err := conn.Exec("UPDATE schema_migrations SET checksum = ? WHERE version = ?", m.checksum, m.version); err != nil {
+				return fmt.Errorf("error recording checksum of migration %s: %v", m.version, err)
+			}
+		default:
+			return fmt.Errorf("migration %s was modified after it was applied", m.version)
+		}
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		module, err := unavailableModule(conn, m.version)
+		if err != nil {
+			return err
+		}
+		if module != "" {
+			fmt.Fprintf(out, "Skipping migration %s: SQLite was built without %s, build with -tags sqlite_%s to apply it\n",
+				m.version, module, module)
+			continue
+		}
+
+		fmt.Fprintf(out, "Applying migration %s...\n", m.version)
+
+		err = inTransaction(conn, func(tx SQLExecutor) error {
+			if err := execStatements(tx, m.up); err != nil {
+				return fmt.Errorf("error executing migration %s: %v", m.version, err)
+			}
+
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, checksum, applied_at) VALUES (?, ?, ?)",
+				m.version, m.checksum, sqliteTimestamp(time.Now()))
+			if err != nil {
+				return fmt.Errorf("error recording migration %s: %v", m.version, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations
+// with their down migrations, newest first, writing progress to out. It rolls
+// back at least one migration.
+func MigrateDown(conn SQLExecutor, steps int, out io.Writer) error {
+	if steps < 1 {
+		return fmt.Errorf("invalid number of steps %d: must be at least 1", steps)
+	}
+
+	migrations, err := loadMigrations(conn.Dialect())
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[string]migration)
+	for _, m := range migrations {
+		byVersion[m.version] = m
+	}
+
+	var versions []string
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	for _, version := range versions {
+		m, ok := byVersion[version]
+		if !ok {
+			return fmt.Errorf("cannot roll back migration %s: no such migration in this build", version)
+		}
+		if !m.hasDown {
+			return fmt.Errorf("cannot roll back migration %s: it has no down migration", version)
+		}
+
+		fmt.Fprintf(out, "Rolling back migration %s...\n", version)
+
+		err := inTransaction(conn, func(tx SQLExecutor) error {
+			if err := execStatements(tx, m.down); err != nil {
+				return fmt.Errorf("error rolling back migration %s: %v", version, err)
+			}
+			if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version); err != nil {
+				return fmt.Errorf("error recording rollback of migration %s: %v", version, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureMigrationsTable creates schema_migrations, adding the checksum column
+// to tables created before checksums were recorded
+func ensureMigrationsTable(conn SQLExecutor) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			checksum TEXT,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+
+	var columns int
+	err = conn.QueryRow(conn.Dialect().columnExistsQuery(), "schema_migrations", "checksum").Scan(&columns)
+	if err != nil {
+		return fmt.Errorf("error checking schema_migrations table: %v", err)
+	}
+	if columns == 0 {
+		if _, err := conn.Exec("ALTER TABLE schema_migrations ADD COLUMN checksum TEXT"); err != nil {
+			return fmt.Errorf("error adding checksum to schema_migrations: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// inTransaction runs fn in a new transaction, or directly in conn if it already is one
+func inTransaction(conn SQLExecutor, fn func(tx SQLExecutor) error) error {
+	db, ok := conn.(*DB)
+	if !ok {
+		return fn(conn)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execStatements executes each statement of a migration file
+func execStatements(conn SQLExecutor, content string) error {
+	for _, stmt := range splitStatements(content) {
+		if _, err := conn.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a migration file into statements on the semicolons
+// that end them. Semicolons in string literals, quoted identifiers, comments,
+// PostgreSQL dollar-quoted bodies and the BEGIN ... END body of a CREATE
+// TRIGGER statement do not end a statement. Statements holding nothing but
+// comments are dropped.
+func splitStatements(content string) []string {
+	var statements []string
+	start := 0
+	hasCode := false
+
+	// Keywords of the current statement, to recognize CREATE [TEMP] TRIGGER and
+	// track the BEGIN/CASE ... END blocks of its body
+	keywords, first := 0, ""
+	trigger := false
+	depth := 0
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipPast(content, i+1, string(c))
+			hasCode = true
+		case c == '[':
+			i = skipPast(content, i+1, "]")
+			hasCode = true
+		case strings.HasPrefix(content[i:], "--"):
+			i = skipPast(content, i+2, "\n")
+		case strings.HasPrefix(content[i:], "/*"):
+			i = skipPast(content, i+2, "*/")
+		case c == '$' && dollarTag(content[i:]) != "":
+			tag := dollarTag(content[i:])
+			i = skipPast(content, i+len(tag), tag)
+			hasCode = true
+		case isWordStart(c):
+			end := i + 1
+			for end < len(content) && isWordPart(content[end]) {
+				end++
+			}
+			word := strings.ToUpper(content[i:end])
+			keywords++
+			if keywords == 1 {
+				first = word
+			}
+			switch {
+			case word == "TRIGGER" && keywords <= 3 && first == "CREATE":
+				trigger = true
+			case trigger && (word == "BEGIN" || word == "CASE"):
+				depth++
+			case trigger && word == "END" && depth > 0:
+				depth--
+			}
+			i = end - 1
+			hasCode = true
+		case c == ';' && depth == 0:
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(content[start:i]))
+			}
+			start, hasCode = i+1, false
+			keywords, trigger = 0, false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasCode = true
+		}
+	}
+
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(content[start:]))
+	}
+
+	return statements
+}
+
+// skipPast returns the index of the last byte of the first closing delimiter
+// at or after from, or the end of content if it is never closed
+func skipPast(content string, from int, closing string) int {
+	end := strings.Index(content[from:], closing)
+	if end < 0 {
+		return len(content) - 1
+	}
+	return from + end + len(closing) - 1
+}
+
+// dollarTag returns the $tag$ or $$ opening a PostgreSQL dollar-quoted string
+// at the start of s, or "" if there is none
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isWordPart(s[i]) || (i == 1 && !isWordStart(s[i])) {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9')
+}
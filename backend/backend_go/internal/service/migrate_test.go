@@ -0,0 +1,31 @@
+package service
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDown(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mustNoErr(t, "migrating", Migrate(db, io.Discard))
+
+	for _, steps := range []int{0, -1} {
+		if err := MigrateDown(db, steps, io.Discard); err == nil {
+			t.Fatalf("rolling back %d steps succeeded, want an error", steps)
+		}
+	}
+
+	before, err := appliedMigrations(db)
+	mustNoErr(t, "listing applied migrations", err)
+	mustNoErr(t, "rolling back one step", MigrateDown(db, 1, io.Discard))
+	after, err := appliedMigrations(db)
+	mustNoErr(t, "listing applied migrations", err)
+	if len(after) != len(before)-1 {
+		t.Fatalf("got %d applied migrations after rolling back one of %d", len(after), len(before))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build postgres
+
+package service
+
+// The PostgreSQL driver is only linked into builds with the postgres tag, so
+// SQLite-only builds do not need it
+import _ "github.com/jackc/pgx/v5/stdlib"
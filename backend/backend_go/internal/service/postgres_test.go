@@ -0,0 +1,27 @@
+//go:build postgres
+
+package service
+
+import (
+	"os"
+	"testing"
+)
+
+// TestPostgresStore runs the store scenarios against the PostgreSQL database
+// in TEST_DATABASE_URL and checks that it behaves like SQLite. Every scenario
+// starts with a full reset, so the database must be one set aside for tests.
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if DialectOf(dsn) != Postgres {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL, got %q", dsn)
+	}
+
+	got := runStoreScenarios(t, func(t *testing.T) Store {
+		return openDBStore(t, dsn)
+	})
+	want := runStoreScenarios(t, openSQLiteStore)
+	compareTraces(t, "sqlite", want, "postgres", got)
+}
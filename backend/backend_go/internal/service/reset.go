@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ResetHistory deletes all study sessions, review items and review schedules
+// while keeping words, groups and study activities
+func (s *DBService) ResetHistory() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM word_review_items",
+		"DELETE FROM word_review_schedules",
+		"DELETE FROM study_sessions",
+	}
+	statements = append(statements, s.db.dialect.resetIDs("word_review_items", "study_sessions")...)
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("error resetting study history: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing history reset: %v", err)
+	}
+
+	return nil
+}
+
+// FullReset drops every table and re-applies all migrations and seeds in a
+// single transaction, so a failure leaves the existing data untouched
+func (s *DBService) FullReset() error {
+	ctx := context.Background()
+
+	// Foreign keys can only be toggled outside a transaction, so the reset runs on
+	// a dedicated connection with them switched off while tables are dropped.
+	// PostgreSQL drops the dependent constraints along with each table instead.
+	conn, err := s.db.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %v", err)
+	}
+	defer conn.Close()
+
+	if s.db.dialect == SQLite {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("error disabling foreign keys: %v", err)
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	sqlTx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	tx := &Tx{tx: sqlTx, dialect: s.db.dialect}
+	defer tx.Rollback()
+
+	if err := DropTables(tx); err != nil {
+		return err
+	}
+	if err := Migrate(tx, io.Discard); err != nil {
+		return err
+	}
+	if _, err := Seed(tx, io.Discard, false); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing full reset: %v", err)
+	}
+
+	return nil
+}
+
+// DropTables drops every table in the database
+func DropTables(conn SQLExecutor) error {
+	rows, err := conn.Query(conn.Dialect().tablesQuery())
+	if err != nil {
+		return fmt.Errorf("error listing tables: %v", err)
+	}
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning table name: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error listing tables: %v", err)
+	}
+
+	for _, table := range tables {
+		if _, err := conn.Exec(conn.Dialect().dropTable(table)); err != nil {
+			return fmt.Errorf("error dropping table %s: %v", table, err)
+		}
+	}
+
+	return nil
+}
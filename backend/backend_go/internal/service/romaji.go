@@ -0,0 +1,205 @@
+package service
+
+import "strings"
+
+// romajiKana maps Hepburn (and common Kunrei) romaji syllables to hiragana
+var romajiKana = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"sa": "さ", "shi": "し", "si": "し", "su": "す", "se": "せ", "so": "そ",
+	"ta": "た", "chi": "ち", "ti": "ち", "tsu": "つ", "tu": "つ", "te": "て", "to": "と",
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"ha": "は", "hi": "ひ", "fu": "ふ", "hu": "ふ", "he": "へ", "ho": "ほ",
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"wa": "わ", "wo": "を",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"za": "ざ", "ji": "じ", "zi": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ",
+	"sha": "しゃ", "shu": "しゅ", "sho": "しょ", "sya": "しゃ", "syu": "しゅ", "syo": "しょ",
+	"cha": "ちゃ", "chu": "ちゅ", "cho": "ちょ", "tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ",
+	"hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ",
+	"rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+	"gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ",
+	"ja": "じゃ", "ju": "じゅ", "jo": "じょ", "zya": "じゃ", "zyu": "じゅ", "zyo": "じょ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ",
+	"pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+	"-": "ー",
+}
+
+// romajiToHiragana converts romaji to hiragana. Trailing consonants that do not
+// form a full syllable yet are dropped so partially typed words still convert.
+// It returns false if the input is not romaji.
+func romajiToHiragana(romaji string) (string, bool) {
+	input := strings.ToLower(romaji)
+	var out strings.Builder
+
+	for i := 0; i < len(input); {
+		rest := input[i:]
+
+		// n before a consonant, n', or a final n is ん
+		if rest[0] == 'n' {
+			if len(rest) == 1 {
+				out.WriteString("ん")
+				break
+			}
+			if rest[1] == '\'' || (rest[1] == 'n' && (len(rest) == 2 || !isRomajiVowel(rest[2]) && rest[2] != 'y')) {
+				out.WriteString("ん")
+				i += 2
+				continue
+			}
+			if !isRomajiVowel(rest[1]) && rest[1] != 'y' {
+				out.WriteString("ん")
+				i++
+				continue
+			}
+		}
+
+		// Doubled consonants (and tch) start with a small tsu
+		doubled := len(rest) > 1 && rest[0] == rest[1] && rest[0] != 'n' && rest[0] != '-' && !isRomajiVowel(rest[0])
+		if doubled || strings.HasPrefix(rest, "tch") {
+			out.WriteString("っ")
+			i++
+			continue
+		}
+
+		matched := false
+		for size := 3; size > 0; size-- {
+			if size > len(rest) {
+				continue
+			}
+			if kana, ok := romajiKana[rest[:size]]; ok {
+				out.WriteString(kana)
+				i += size
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		if isRomajiIncomplete(rest) {
+			break
+		}
+		return "", false
+	}
+
+	return out.String(), out.Len() > 0
+}
+
+// hiraganaToKatakana shifts hiragana into the katakana block
+func hiraganaToKatakana(hiragana string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ぁ' && r <= 'ゖ' {
+			return r + 0x60
+		}
+		return r
+	}, hiragana)
+}
+
+// isRomajiVowel reports whether c is a romaji vowel
+func isRomajiVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
+
+// isRomajiIncomplete reports whether rest is the start of a syllable that has
+// not been fully typed yet, e.g. "ky" or "ts"
+func isRomajiIncomplete(rest string) bool {
+	if len(rest) > 2 {
+		return false
+	}
+	for syllable := range romajiKana {
+		if len(syllable) > len(rest) && strings.HasPrefix(syllable, rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// kanaRomaji maps hiragana to Hepburn romaji, the reverse of romajiKana
+var kanaRomaji = func() map[string]string {
+	kunrei := map[string]bool{
+		"si": true, "ti": true, "tu": true, "hu": true, "zi": true,
+		"sya": true, "syu": true, "syo": true, "tya": true, "tyu": true, "tyo": true,
+		"zya": true, "zyu": true, "zyo": true,
+	}
+	kana := map[string]string{"ん": "n", "ゐ": "i", "ゑ": "e", "ゔ": "vu"}
+	for romaji, hiragana := range romajiKana {
+		if !kunrei[romaji] {
+			kana[hiragana] = romaji
+		}
+	}
+	return kana
+}()
+
+// kanaToRomaji converts hiragana and katakana to Hepburn romaji. It returns
+// false if the input holds anything else, such as kanji.
+func kanaToRomaji(kana string) (string, bool) {
+	input := []rune(katakanaToHiragana(kana))
+	var out strings.Builder
+
+	for i := 0; i < len(input); {
+		// Small tsu doubles the next consonant
+		if input[i] == 'っ' {
+			if i+1 < len(input) {
+				if next, ok := kanaRomaji[string(input[i+1])]; ok && !isRomajiVowel(next[0]) {
+					out.WriteByte(next[0])
+				}
+			}
+			i++
+			continue
+		}
+
+		// The long vowel mark repeats the vowel before it
+		if input[i] == 'ー' {
+			if current := out.String(); current != "" && isRomajiVowel(current[len(current)-1]) {
+				out.WriteByte(current[len(current)-1])
+				i++
+				continue
+			}
+		}
+		if i+1 < len(input) {
+			if romaji, ok := kanaRomaji[string(input[i:i+2])]; ok {
+				out.WriteString(romaji)
+				i += 2
+				continue
+			}
+		}
+		if romaji, ok := kanaRomaji[string(input[i])]; ok {
+			// ん before a vowel or y is written n' to keep the syllables apart
+			if romaji == "n" && i+1 < len(input) {
+				if next, ok := kanaRomaji[string(input[i+1])]; ok && (isRomajiVowel(next[0]) || next[0] == 'y') {
+					romaji = "n'"
+				}
+			}
+			out.WriteString(romaji)
+			i++
+			continue
+		}
+		if input[i] == ' ' || input[i] == '　' {
+			out.WriteByte(' ')
+			i++
+			continue
+		}
+		return "", false
+	}
+
+	return out.String(), out.Len() > 0
+}
+
+// katakanaToHiragana shifts katakana into the hiragana block
+func katakanaToHiragana(katakana string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - 0x60
+		}
+		return r
+	}, katakana)
+}
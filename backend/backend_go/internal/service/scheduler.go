@@ -0,0 +1,80 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+)
+
+const (
+	// defaultEaseFactor is the ease factor assigned to a word on its first review
+	defaultEaseFactor = 2.5
+	// minEaseFactor keeps difficult words from being scheduled too aggressively
+	minEaseFactor = 1.3
+	// passingQuality is the lowest SM-2 quality that counts as a successful recall
+	passingQuality = 3
+)
+
+// reviewQuality maps a review onto the SM-2 0-5 quality scale. An explicit grade
+// wins; otherwise correct answers count as 4, or 3 when a hint was used.
+func reviewQuality(review models.WordReviewItem) int {
+	if review.Grade != nil {
+		return *review.Grade
+	}
+	if !review.Correct {
+		return 1
+	}
+	if review.HintUsed {
+		return 3
+	}
+	return 4
+}
+
+// NextReviewSchedule computes the schedule that follows a review of the given
+// quality (0-5) using the SM-2 algorithm. A nil previous schedule means the word
+// has never been reviewed before.
+func NextReviewSchedule(wordID int64, prev *models.ReviewSchedule, quality int, now time.Time) models.ReviewSchedule {
+	next := models.ReviewSchedule{
+		WordID:     wordID,
+		EaseFactor: defaultEaseFactor,
+	}
+	if prev != nil {
+		next.EaseFactor = prev.EaseFactor
+		next.IntervalDays = prev.IntervalDays
+		next.Repetitions = prev.Repetitions
+	}
+
+	if quality < 0 {
+		quality = 0
+	}
+	if quality > 5 {
+		quality = 5
+	}
+
+	if quality < passingQuality {
+		// Failed recall starts the word over without touching its ease
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	} else {
+		next.Repetitions++
+		switch next.Repetitions {
+		case 1:
+			next.IntervalDays = 1
+		case 2:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+
+		q := float64(5 - quality)
+		next.EaseFactor += 0.1 - q*(0.08+q*0.02)
+		if next.EaseFactor < minEaseFactor {
+			next.EaseFactor = minEaseFactor
+		}
+	}
+
+	next.LastReviewedAt = now
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return next
+}
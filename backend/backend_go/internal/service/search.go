@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// SearchWords finds words whose Japanese, romaji or English text starts with the
+// query terms, best matches first. Romaji terms also match the kana spelling.
+// SQLite builds without FTS5 match the terms with LIKE instead of the full-text
+// index, see likeMatches.
+func (s *DBService) SearchWords(q string, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error) {
+	words := []models.WordWithStats{}
+
+	fullText := true
+	if s.db.dialect == SQLite {
+		var err error
+		if fullText, err = sqliteHasModule(s.db, "fts5"); err != nil {
+			return nil, models.PageInfo{}, err
+		}
+	}
+	matches, args := searchMatches(s.db.dialect, q, fullText)
+	if len(args) == 0 {
+		return words, models.PageInfo{Page: options.Page, PageSize: options.PageSize, CursorMode: options.CursorMode}, nil
+	}
+
+	// Japanese and romaji matches rank above English ones
+	list := listQuery{
+		base: `
+			WITH m AS MATERIALIZED (` + matches + `)
+			SELECT
+				w.id, w.japanese, w.romaji, w.english, w.parts,
+				COALESCE(SUM(CASE WHEN wr.correct THEN 1 ELSE 0 END), 0) as correct_count,
+				COALESCE(SUM(CASE WHEN NOT wr.correct THEN 1 ELSE 0 END), 0) as wrong_count,
+				MAX(wr.created_at) as last_studied_at,
+				m.score
+			FROM m
+			JOIN words w ON w.id = m.rowid
+			LEFT JOIN word_review_items wr ON w.id = wr.word_id
+			GROUP BY w.id, m.score
+		`,
+		args:        args,
+		columns:     wordStatsColumns,
+		defaultSort: "score",
+	}
+
+	rows, err := s.queryList(list, options)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error searching words: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word models.WordWithStats
+		err := rows.Scan(
+			&word.ID,
+			&word.Japanese,
+			&word.Romaji,
+			&word.English,
+			&word.Parts,
+			&word.CorrectCount,
+			&word.WrongCount,
+		)
+		if err != nil {
+			return nil, models.PageInfo{}, fmt.Errorf("error scanning word row: %v", err)
+		}
+		words = append(words, word)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("error searching words: %v", err)
+	}
+
+	return words, rows.PageInfo(), nil
+}
+
+// searchMatches returns a query selecting the rowid and score of the words
+// matching q, lower scores first, together with its arguments. There are no
+// arguments if q has no terms. fullText selects SQLite's FTS5 index over
+// LIKE matching.
+func searchMatches(d Dialect, q string, fullText bool) (string, []any) {
+	var query, match string
+	switch {
+	case d == Postgres:
+		query, match = `
+			SELECT w.id as rowid, -ts_rank('{0.1, 0.2, 0.5, 1.0}', w.search, query) as score
+			FROM words w, to_tsquery('simple', ?) query
+			WHERE w.search @@ query
+		`, tsQueryExpression(q)
+	case !fullText:
+		return likeMatches(q)
+	default:
+		query, match = `
+			SELECT rowid, bm25(words_fts, 2.0, 2.0, 1.0) as score
+			FROM words_fts
+			WHERE words_fts MATCH ?
+		`, searchMatchExpression(q)
+	}
+	if match == "" {
+		return query, nil
+	}
+	return query, []any{match}
+}
+
+// likeMatches is the counterpart of searchMatches for SQLite builds without
+// FTS5. Like MemoryStore's search, every term must match the start of the
+// Japanese, romaji or English text or of a word in it after a space, and every
+// field a term matches lowers the score by the field's weight.
+func likeMatches(q string) (string, []any) {
+	fields := []struct {
+		column string
+		weight int
+	}{{"japanese", 2}, {"romaji", 2}, {"english", 1}}
+
+	var args []any
+	var scores, totals, conditions []string
+	for i, alternatives := range searchTerms(q) {
+		var fieldScores []string
+		for _, field := range fields {
+			var likes []string
+			for _, alternative := range alternatives {
+				pattern := likeEscaper.Replace(alternative) + "%"
+				likes = append(likes, fmt.Sprintf(`w.%[1]s LIKE ? ESCAPE '\' OR w.%[1]s LIKE ? ESCAPE '\'`, field.column))
+				args = append(args, pattern, "% "+pattern)
+			}
+			fieldScores = append(fieldScores, fmt.Sprintf("CASE WHEN %s THEN %d ELSE 0 END", strings.Join(likes, " OR "), field.weight))
+		}
+		term := fmt.Sprintf("t%d", i)
+		scores = append(scores, strings.Join(fieldScores, " + ")+" as "+term)
+		totals = append(totals, term)
+		conditions = append(conditions, term+" > 0")
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	return `
+		SELECT id as rowid, -(` + strings.Join(totals, " + ") + `) as score
+		FROM (SELECT w.id, ` + strings.Join(scores, ", ") + ` FROM words w)
+		WHERE ` + strings.Join(conditions, " AND ") + `
+	`, args
+}
+
+// likeEscaper escapes the LIKE wildcards of a term for ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// sqliteHasModule reports whether SQLite was compiled with an optional module
+// such as fts5, which mattn/go-sqlite3 only includes with a build tag
+func sqliteHasModule(conn SQLExecutor, module string) (bool, error) {
+	var used bool
+	if err := conn.QueryRow("SELECT sqlite_compileoption_used(?)", "ENABLE_"+strings.ToUpper(module)).Scan(&used); err != nil {
+		return false, fmt.Errorf("error checking SQLite module %s: %v", module, err)
+	}
+	return used, nil
+}
+
+// searchMatchExpression turns a free-text query into an FTS5 MATCH expression in
+// which every term must match as a prefix. It returns "" if q has no terms.
+func searchMatchExpression(q string) string {
+	var terms []string
+	for _, alternatives := range searchTerms(q) {
+		for i, alternative := range alternatives {
+			alternatives[i] = ftsPrefix(alternative)
+		}
+		terms = append(terms, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// searchTerms splits a free-text query into terms, each with the spellings it
+// may match. Terms that read as romaji may also match their hiragana or
+// katakana spelling.
+func searchTerms(q string) [][]string {
+	var terms [][]string
+	for _, term := range strings.Fields(q) {
+		term = strings.ReplaceAll(term, `"`, "")
+		if term == "" {
+			continue
+		}
+		alternatives := []string{term}
+		if hiragana, ok := romajiToHiragana(term); ok {
+			alternatives = append(alternatives, hiragana, hiraganaToKatakana(hiragana))
+		}
+		terms = append(terms, alternatives)
+	}
+	return terms
+}
+
+// ftsPrefix quotes a term as an FTS5 prefix query
+func ftsPrefix(term string) string {
+	return `"` + term + `"*`
+}
+
+// tsQueryExpression is the PostgreSQL tsquery counterpart of searchMatchExpression
+func tsQueryExpression(q string) string {
+	var terms []string
+	for _, alternatives := range searchTerms(q) {
+		for i, alternative := range alternatives {
+			alternatives[i] = tsPrefix(alternative)
+		}
+		terms = append(terms, "("+strings.Join(alternatives, " | ")+")")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// tsPrefix quotes a term as a tsquery prefix match
+func tsPrefix(term string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(term) + "':*"
+}
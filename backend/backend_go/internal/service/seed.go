@@ -0,0 +1,364 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"pengyou-chinese/backend/db"
+)
+
+// SeedGroup represents a group in the seed file
+type SeedGroup struct {
+	Name string `json:"name"`
+}
+
+// SeedWord represents a word in the seed file
+type SeedWord struct {
+	Japanese string `json:"japanese"`
+	Romaji   string `json:"romaji"`
+	English  string `json:"english"`
+	Parts    string `json:"parts"`
+}
+
+// SeedFile represents the structure of a word group seed file
+type SeedFile struct {
+	Group SeedGroup  `json:"group"`
+	Words []SeedWord `json:"words"`
+}
+
+// ActivitySeedFile represents the structure of the activities seed file
+type ActivitySeedFile struct {
+	Activities []struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		ThumbnailURL string `json:"thumbnail_url"`
+		LaunchURL    string `json:"launch_url"`
+	} `json:"activities"`
+}
+
+// SeedCounts counts the seed records of one kind by what seeding did with them
+type SeedCounts struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+// SeedReport summarizes a seed run
+type SeedReport struct {
+	Activities SeedCounts
+	Groups     SeedCounts
+	Words      SeedCounts
+	GroupWords SeedCounts
+}
+
+// Seed imports the embedded JSON seed files, writing each change to out.
+// Records are matched on their natural keys, so seeding again only creates
+// what is missing and updates what changed: activities by ID, groups by name
+// and words by their Japanese and English. With dryRun the changes are
+// reported but rolled back.
+func Seed(conn SQLExecutor, out io.Writer, dryRun bool) (*SeedReport, error) {
+	activityFiles, groupFiles, err := seedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &seeder{out: out, dryRun: dryRun}
+	seed := func(tx SQLExecutor) error {
+		s.conn = tx
+
+		// Process activities first
+		for _, file := range activityFiles {
+			if err := s.seedActivities(file); err != nil {
+				return err
+			}
+		}
+
+		// Then process word groups
+		for _, file := range groupFiles {
+			if err := s.seedWordGroup(file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if dryRun {
+		err = inRolledBackTransaction(conn, seed)
+	} else {
+		err = inTransaction(conn, seed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s.report, nil
+}
+
+// inRolledBackTransaction runs fn in a new transaction, or a savepoint if conn
+// already is a transaction, and then discards its changes
+func inRolledBackTransaction(conn SQLExecutor, fn func(tx SQLExecutor) error) error {
+	db, ok := conn.(*DB)
+	if !ok {
+		if _, err := conn.Exec("SAVEPOINT dry_run"); err != nil {
+			return fmt.Errorf("error starting savepoint: %v", err)
+		}
+		defer conn.Exec("RELEASE SAVEPOINT dry_run")
+		defer conn.Exec("ROLLBACK TO SAVEPOINT dry_run")
+		return fn(conn)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+// seeder applies seed files to a database, recording what changed
+type seeder struct {
+	conn   SQLExecutor
+	out    io.Writer
+	dryRun bool
+	report SeedReport
+}
+
+// created records and reports a new record
+func (s *seeder) created(counts *SeedCounts, record string) {
+	counts.Created++
+	verb := "Created"
+	if s.dryRun {
+		verb = "Would create"
+	}
+	fmt.Fprintf(s.out, "  %s %s\n", verb, record)
+}
+
+// updated records and reports a changed record, or counts it as unchanged if
+// none of its fields changed
+func (s *seeder) updated(counts *SeedCounts, record string, changes []string) {
+	if len(changes) == 0 {
+		counts.Unchanged++
+		return
+	}
+	counts.Updated++
+	verb := "Updated"
+	if s.dryRun {
+		verb = "Would update"
+	}
+	fmt.Fprintf(s.out, "  %s %s: %s\n", verb, record, strings.Join(changes, ", "))
+}
+
+// fieldChanges describes the fields whose stored value differs from the seed
+// value. Its arguments are triples of field name, stored value and seed value.
+func fieldChanges(fields ...string) []string {
+	var changes []string
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+1] != fields[i+2] {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", fields[i], fields[i+1], fields[i+2]))
+		}
+	}
+	return changes
+}
+
+// seedFiles lists the embedded activity and word group seed files
+func seedFiles() (activityFiles, groupFiles []string, err error) {
+	files, err := fs.Glob(db.Seeds, "seeds/*.json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding seed files: %v", err)
+	}
+
+	for _, file := range files {
+		if strings.Contains(file, "activities") {
+			activityFiles = append(activityFiles, file)
+		} else {
+			groupFiles = append(groupFiles, file)
+		}
+	}
+
+	return activityFiles, groupFiles, nil
+}
+
+// readSeedFile parses an embedded seed file into v
+func readSeedFile(file string, v any) error {
+	content, err := fs.ReadFile(db.Seeds, file)
+	if err != nil {
+		return fmt.Errorf("error reading seed file %s: %v", file, err)
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("error parsing seed file %s: %v", file, err)
+	}
+
+	return nil
+}
+
+func (s *seeder) seedActivities(file string) error {
+	var seedFile ActivitySeedFile
+	if err := readSeedFile(file, &seedFile); err != nil {
+		return err
+	}
+
+	for _, activity := range seedFile.Activities {
+		record := fmt.Sprintf("activity %d '%s'", activity.ID, activity.Name)
+
+		// Activities are keyed by ID since study sessions reference them by their seeded ID
+		var name, description, thumbnailURL, launchURL string
+		err := s.conn.QueryRow(`
+			SELECT name, description, thumbnail_url, launch_url
+			FROM study_activities
+			WHERE id = ?
+		`, activity.ID).Scan(&name, &description, &thumbnailURL, &launchURL)
+		if err == sql.ErrNoRows {
+			_, err = s.conn.Exec(`
+				INSERT INTO study_activities (id, name, description, thumbnail_url, launch_url)
+				VALUES (?, ?, ?, ?, ?)
+			`, activity.ID, activity.Name, activity.Description, activity.ThumbnailURL, activity.LaunchURL)
+			if err != nil {
+				return fmt.Errorf("error inserting activity: %v", err)
+			}
+			s.created(&s.report.Activities, record)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error getting activity: %v", err)
+		}
+
+		changes := fieldChanges(
+			"name", name, activity.Name,
+			"description", description, activity.Description,
+			"thumbnail_url", thumbnailURL, activity.ThumbnailURL,
+			"launch_url", launchURL, activity.LaunchURL,
+		)
+		if len(changes) > 0 {
+			_, err = s.conn.Exec(`
+				UPDATE study_activities
+				SET name = ?, description = ?, thumbnail_url = ?, launch_url = ?
+				WHERE id = ?
+			`, activity.Name, activity.Description, activity.ThumbnailURL, activity.LaunchURL, activity.ID)
+			if err != nil {
+				return fmt.Errorf("error updating activity: %v", err)
+			}
+		}
+		s.updated(&s.report.Activities, record, changes)
+	}
+
+	for _, stmt := range s.conn.Dialect().syncIDs("study_activities") {
+		if _, err := s.conn.Exec(stmt); err != nil {
+			return fmt.Errorf("error updating activity IDs: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *seeder) seedWordGroup(file string) error {
+	var seedFile SeedFile
+	if err := readSeedFile(file, &seedFile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.out, "Seeding group '%s' with %d words\n", seedFile.Group.Name, len(seedFile.Words))
+
+	// Groups are keyed by name
+	var groupID int64
+	err := s.conn.QueryRow("SELECT id FROM groups WHERE name = ? ORDER BY id LIMIT 1", seedFile.Group.Name).Scan(&groupID)
+	switch {
+	case err == sql.ErrNoRows:
+		err = s.conn.QueryRow(`
+			INSERT INTO groups (name)
+			VALUES (?)
+			RETURNING id
+		`, seedFile.Group.Name).Scan(&groupID)
+		if err != nil {
+			return fmt.Errorf("error inserting group: %v", err)
+		}
+		s.created(&s.report.Groups, fmt.Sprintf("group '%s'", seedFile.Group.Name))
+	case err != nil:
+		return fmt.Errorf("error getting group: %v", err)
+	default:
+		s.report.Groups.Unchanged++
+	}
+
+	for _, word := range seedFile.Words {
+		if _, err := s.seedGroupWord(groupID, seedFile.Group.Name, word); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedGroupWord creates or updates a word, see seedWord, and adds it to a
+// group. It returns the word's ID.
+func (s *seeder) seedGroupWord(groupID int64, groupName string, word SeedWord) (int64, error) {
+	wordID, err := s.seedWord(word)
+	if err != nil {
+		return 0, err
+	}
+
+	var memberships int
+	err = s.conn.QueryRow(`
+		SELECT COUNT(*) FROM words_groups WHERE word_id = ? AND group_id = ?
+	`, wordID, groupID).Scan(&memberships)
+	if err != nil {
+		return 0, fmt.Errorf("error checking word-group association: %v", err)
+	}
+	if memberships > 0 {
+		s.report.GroupWords.Unchanged++
+		return wordID, nil
+	}
+
+	_, err = s.conn.Exec(`
+		INSERT INTO words_groups (word_id, group_id)
+		VALUES (?, ?)
+	`, wordID, groupID)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting word-group association: %v", err)
+	}
+	s.created(&s.report.GroupWords, fmt.Sprintf("word %s (%s) in group '%s'", word.Japanese, word.English, groupName))
+	return wordID, nil
+}
+
+// seedWord creates or updates a word, keyed by its Japanese and English, and returns its ID
+func (s *seeder) seedWord(word SeedWord) (int64, error) {
+	record := fmt.Sprintf("word %s (%s)", word.Japanese, word.English)
+
+	var wordID int64
+	var romaji, parts string
+	err := s.conn.QueryRow(`
+		SELECT id, romaji, COALESCE(parts, '')
+		FROM words
+		WHERE japanese = ? AND english = ?
+		ORDER BY id
+		LIMIT 1
+	`, word.Japanese, word.English).Scan(&wordID, &romaji, &parts)
+	if err == sql.ErrNoRows {
+		err = s.conn.QueryRow(`
+			INSERT INTO words (japanese, romaji, english, parts)
+			VALUES (?, ?, ?, ?)
+			RETURNING id
+		`, word.Japanese, word.Romaji, word.English, word.Parts).Scan(&wordID)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting word: %v", err)
+		}
+		s.created(&s.report.Words, record)
+		return wordID, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting word: %v", err)
+	}
+
+	changes := fieldChanges("romaji", romaji, word.Romaji, "parts", parts, word.Parts)
+	if len(changes) > 0 {
+		_, err = s.conn.Exec("UPDATE words SET romaji = ?, parts = ? WHERE id = ?", word.Romaji, word.Parts, wordID)
+		if err != nil {
+			return 0, fmt.Errorf("error updating word: %v", err)
+		}
+	}
+	s.updated(&s.report.Words, record, changes)
+	return wordID, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+)
+
+// StaleSessionTimeout is the shortest time an active study session may go
+// without a review before it is considered abandoned. It matches the lifetime
+// of a launched study app's session token, so a session is not abandoned
+// while its app may still send reviews.
+const StaleSessionTimeout = SessionTokenTTL
+
+// AnswerClockSkew is how far the answer time of a review may lie after the
+// server's clock or before the start of its session, allowing for clients
+// whose clocks are slightly off
+const AnswerClockSkew = time.Minute
+
+// setSessionTimes fills in the derived start/end time and duration of a session.
+// Sessions that are still active report their duration up to now.
+func setSessionTimes(session *models.StudySession, endedAt sql.NullTime, now time.Time) {
+	session.StartTime = session.CreatedAt
+	end := now
+	if endedAt.Valid {
+		session.EndTime = &endedAt.Time
+		end = endedAt.Time
+	}
+
+	session.Duration = int64(end.Sub(session.StartTime).Seconds())
+	if session.Duration < 0 {
+		session.Duration = 0
+	}
+}
+
+// checkAnswerTimes checks the answer times of a batch of reviews for a session
+// that started at startedAt. Times in the future beyond AnswerClockSkew, or
+// before the session started by more than that, give ErrAnswerTimeOutOfRange
+// naming the first such review. Times within the skew of now are moved back
+// to now, so reviews are never recorded in the future.
+func checkAnswerTimes(reviews []models.WordReviewItem, startedAt, now time.Time) ([]models.WordReviewItem, error) {
+	checked := make([]models.WordReviewItem, len(reviews))
+	for i, review := range reviews {
+		switch {
+		case review.CreatedAt.After(now.Add(AnswerClockSkew)):
+			return nil, fmt.Errorf("%w: reviews[%d].answered_at is in the future", ErrAnswerTimeOutOfRange, i)
+		case review.CreatedAt.Before(startedAt.Add(-AnswerClockSkew)):
+			return nil, fmt.Errorf("%w: reviews[%d].answered_at is before the study session started", ErrAnswerTimeOutOfRange, i)
+		case review.CreatedAt.After(now):
+			review.CreatedAt = now.UTC()
+		}
+		checked[i] = review
+	}
+	return checked, nil
+}
+
+// reviewOrder returns the indexes of a batch of reviews in the order they were
+// answered, keeping the order of the batch for reviews answered at once
+func reviewOrder(reviews []models.WordReviewItem) []int {
+	order := make([]int, len(reviews))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return reviews[order[a]].CreatedAt.Before(reviews[order[b]].CreatedAt)
+	})
+	return order
+}
+
+// FinishStudySession marks an active study session as finished. It returns nil
+// if the session does not exist and ErrSessionNotActive if it was already closed.
+func (s *DBService) FinishStudySession(id int64) (*models.StudySession, error) {
+	query := `
+		UPDATE study_sessions
+		SET status = ?, ended_at = ?
+		WHERE id = ? AND status = ?
+	`
+
+	endedAt := sqliteTimestamp(time.Now())
+	result, err := s.db.Exec(query, models.SessionStatusFinished, endedAt, id, models.SessionStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("error finishing study session: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error finishing study session: %v", err)
+	}
+
+	session, err := s.GetStudySession(id)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, ErrSessionNotActive
+	}
+
+	return session, nil
+}
+
+// ExpireStaleSessions marks active study sessions without any review in the
+// given timeout as abandoned, ending them at their last review. It returns the
+// number of expired sessions.
+func (s *DBService) ExpireStaleSessions(timeout time.Duration) (int64, error) {
+	lastActivity := `
+		COALESCE(
+			(SELECT MAX(wr.created_at) FROM word_review_items wr WHERE wr.study_session_id = study_sessions.id),
+			study_sessions.created_at
+		)
+	`
+	query := `
+		UPDATE study_sessions
+		SET status = ?, ended_at = ` + lastActivity + `
+		WHERE status = ? AND ` + lastActivity + ` < ?
+	`
+
+	cutoff := sqliteTimestamp(time.Now().Add(-timeout))
+	result, err := s.db.Exec(query, models.SessionStatusAbandoned, models.SessionStatusActive, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring stale study sessions: %v", err)
+	}
+
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error expiring stale study sessions: %v", err)
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SupportsSnapshots reports whether Snapshot can copy the database, which is
+// only the case for SQLite
+func (db *DB) SupportsSnapshots() bool {
+	return db.dialect == SQLite
+}
+
+// Snapshot writes a consistent copy of the database to a new SQLite file at
+// path with SQLite's online backup API, which copies the database while other
+// connections keep using it. Other databases return ErrSnapshotsUnsupported.
+func (db *DB) Snapshot(path string) error {
+	if !db.SupportsSnapshots() {
+		return ErrSnapshotsUnsupported
+	}
+
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot: %v", err)
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot: %v", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := db.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			backup, err := destDriverConn.(*sqlite3.SQLiteConn).Backup("main", srcDriverConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return fmt.Errorf("error starting snapshot: %v", err)
+			}
+
+			// Copy every page in one step, which reads the database in a
+			// single transaction so that the copy is consistent
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return fmt.Errorf("error writing snapshot: %v", err)
+			}
+			if err := backup.Finish(); err != nil {
+				return fmt.Errorf("error writing snapshot: %v", err)
+			}
+			return nil
+		})
+	})
+}
+
+// SupportsSnapshots reports whether Snapshot can copy the database, see DB.SupportsSnapshots
+func (s *DBService) SupportsSnapshots() bool {
+	return s.db.SupportsSnapshots()
+}
+
+// Snapshot writes a consistent copy of the database to path, see DB.Snapshot
+func (s *DBService) Snapshot(path string) error {
+	return s.db.Snapshot(path)
+}
+
+// SupportsSnapshots returns false since there is no database file to copy
+func (m *MemoryStore) SupportsSnapshots() bool {
+	return false
+}
+
+// Snapshot returns ErrSnapshotsUnsupported since there is no database file to copy
+func (m *MemoryStore) Snapshot(path string) error {
+	return ErrSnapshotsUnsupported
+}
+
+// Snapshotter is a database that can write snapshots of itself
+type Snapshotter interface {
+	SupportsSnapshots() bool
+	Snapshot(path string) error
+}
+
+// Snapshots writes database snapshots named <Prefix>-<UTC time>.db into Dir
+// and keeps the Keep most recent of them, or all of them when Keep is 0
+type Snapshots struct {
+	Dir    string
+	Prefix string
+	Keep   int
+}
+
+// SnapshotPrefix names the snapshots of a SQLite database after its file
+func SnapshotPrefix(dsn string) string {
+	name, _, _ := strings.Cut(filepath.Base(dsn), "?")
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." {
+		return "database"
+	}
+	return name
+}
+
+// snapshotTimeFormat is the time in snapshot file names, which sorts them by
+// age. Milliseconds keep the names of snapshots taken in quick succession apart.
+const snapshotTimeFormat = "20060102-150405.000"
+
+// snapshotNameAttempts bounds the retries for a snapshot name that is taken
+const snapshotNameAttempts = 10
+
+// Take writes a snapshot of db and then removes the oldest snapshots beyond
+// the retention count. Existing snapshots are never overwritten: if the name
+// for the current time is taken, the next millisecond is tried. Databases
+// without snapshots return ErrSnapshotsUnsupported before anything is written.
+func (s Snapshots) Take(db Snapshotter) (*models.Snapshot, error) {
+	if !db.SupportsSnapshots() {
+		return nil, ErrSnapshotsUnsupported
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating snapshot directory: %v", err)
+	}
+
+	// Write under a temporary name so that an interrupted snapshot is never
+	// taken for a complete one
+	file, err := os.CreateTemp(s.Dir, s.Prefix+"-*.partial")
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot: %v", err)
+	}
+	partial := file.Name()
+	file.Close()
+	defer os.Remove(partial)
+	if err := db.Snapshot(partial); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	snapshot := &models.Snapshot{Removed: []string{}}
+	for attempt := 0; ; attempt++ {
+		snapshot.CreatedAt = now
+		snapshot.File = fmt.Sprintf("%s-%s.db", s.Prefix, now.Format(snapshotTimeFormat))
+		snapshot.Path = filepath.Join(s.Dir, snapshot.File)
+
+		err := saveSnapshot(partial, snapshot.Path)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) || attempt == snapshotNameAttempts-1 {
+			return nil, fmt.Errorf("error saving snapshot: %v", err)
+		}
+		now = now.Add(time.Millisecond)
+	}
+
+	info, err := os.Stat(snapshot.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error saving snapshot: %v", err)
+	}
+	snapshot.SizeBytes = info.Size()
+
+	snapshot.Removed, err = s.prune()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// linkFile creates a hard link, replaced in tests to act like filesystems
+// without hard links
+var linkFile = os.Link
+
+// saveSnapshot gives the snapshot written to partial its final name without
+// replacing an existing file, which returns an error for which os.IsExist is
+// true. Unlike a rename, a link fails rather than replace the file; where
+// hard links are unsupported the snapshot is copied to a file created
+// exclusively instead.
+func saveSnapshot(partial, path string) error {
+	err := linkFile(partial, path)
+	if err == nil || os.IsExist(err) {
+		return err
+	}
+
+	src, err := os.Open(partial)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// prune removes the oldest snapshots beyond the retention count and returns
+// their file names
+func (s Snapshots) prune() ([]string, error) {
+	removed := []string{}
+	if s.Keep <= 0 {
+		return removed, nil
+	}
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots: %v", err)
+	}
+	// Snapshots named before milliseconds were added are pruned as well
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(s.Prefix) + `-\d{8}-\d{6}(\.\d{3})?\.db$`)
+	var snapshots []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && pattern.MatchString(entry.Name()) {
+			snapshots = append(snapshots, entry.Name())
+		}
+	}
+	// Compare the names without the extension, so that a name without
+	// milliseconds sorts before those with them from the same second
+	slices.SortFunc(snapshots, func(a, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, ".db"), strings.TrimSuffix(b, ".db"))
+	})
+
+	for len(snapshots) > s.Keep {
+		if err := os.Remove(filepath.Join(s.Dir, snapshots[0])); err != nil {
+			return nil, fmt.Errorf("error removing old snapshot: %v", err)
+		}
+		removed = append(removed, snapshots[0])
+		snapshots = snapshots[1:]
+	}
+	return removed, nil
+}
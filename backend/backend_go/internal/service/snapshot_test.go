@@ -0,0 +1,86 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSnapshots(t *testing.T) {
+	db := openSQLiteStore(t)
+	dir := t.TempDir()
+
+	// A snapshot from before snapshot names had milliseconds is the oldest
+	old := filepath.Join(dir, "test-20200101-000000.db")
+	if err := os.WriteFile(old, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := Snapshots{Dir: dir, Prefix: "test", Keep: 3}
+	var taken []string
+	for i := 0; i < 5; i++ {
+		snapshot, err := snapshots.Take(db)
+		if err != nil {
+			t.Fatalf("taking snapshot %d: %v", i, err)
+		}
+		if slices.Contains(taken, snapshot.File) {
+			t.Fatalf("snapshot %d reused the name %s", i, snapshot.File)
+		}
+		if snapshot.SizeBytes == 0 {
+			t.Fatalf("snapshot %d is empty", i)
+		}
+		taken = append(taken, snapshot.File)
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), dir) {
+			t.Fatalf("snapshot response %s reveals the snapshot directory", data)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	if want := taken[2:]; !slices.Equal(files, want) {
+		t.Fatalf("got snapshot files %v, want the last 3 snapshots %v", files, want)
+	}
+}
+
+func TestSnapshotsUnsupported(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	snapshots := Snapshots{Dir: dir, Prefix: "test"}
+	if _, err := snapshots.Take(NewMemoryStore()); !errors.Is(err, ErrSnapshotsUnsupported) {
+		t.Fatalf("got %v, want ErrSnapshotsUnsupported", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("the snapshot directory was created for a store without snapshots: %v", err)
+	}
+}
+
+func TestSnapshotsWithoutHardLinks(t *testing.T) {
+	linkFile = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+	}
+	t.Cleanup(func() { linkFile = os.Link })
+
+	db := openSQLiteStore(t)
+	snapshots := Snapshots{Dir: t.TempDir(), Prefix: "test"}
+	first, err := snapshots.Take(db)
+	mustNoErr(t, "taking snapshot", err)
+	second, err := snapshots.Take(db)
+	mustNoErr(t, "taking snapshot", err)
+	if first.File == second.File || first.SizeBytes == 0 || second.SizeBytes != first.SizeBytes {
+		t.Fatalf("got snapshots %+v and %+v, want two complete snapshots", first, second)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// WordStore manages words and their review statistics
+type WordStore interface {
+	GetWords(options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error)
+	SearchWords(q string, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error)
+	GetWord(id int64) (*models.WordDetail, error)
+	GetWordReviews(wordID int64, options validation.ListOptions) ([]models.WordReviewHistoryItem, models.PageInfo, error)
+	CreateWord(word models.Word, groupIDs []int64) (*models.WordDetail, error)
+	UpdateWord(id int64, update models.WordUpdate) (*models.WordDetail, error)
+	DeleteWord(id int64) (bool, error)
+}
+
+// GroupStore manages word groups and their members
+type GroupStore interface {
+	GetGroups(options validation.ListOptions) ([]models.Group, models.PageInfo, error)
+	GetGroup(id int64) (*models.Group, error)
+	GetGroupWords(groupID int64, options validation.ListOptions) ([]models.WordWithStats, models.PageInfo, error)
+	CreateGroup(name string) (*models.Group, error)
+	RenameGroup(id int64, name string) (*models.Group, error)
+	DeleteGroup(id int64) (bool, error)
+	AddWordsToGroup(groupID int64, wordIDs []int64) (int, error)
+	RemoveWordsFromGroup(groupID int64, wordIDs []int64) (int, error)
+}
+
+// SessionStore manages study sessions, the study activity catalog they are
+// launched from and the tokens handed to launched study apps
+type SessionStore interface {
+	GetStudySessions(options validation.ListOptions) ([]models.StudySession, models.PageInfo, error)
+	GetStudySession(id int64) (*models.StudySession, error)
+	GetStudySessionWords(sessionID int64, options validation.ListOptions) ([]models.SessionWord, models.PageInfo, error)
+	CreateStudySession(groupID, studyActivityID int64) (*models.StudySession, error)
+	FinishStudySession(id int64) (*models.StudySession, error)
+	ExpireStaleSessions(timeout time.Duration) (int64, error)
+
+	GetStudyActivities(enabledOnly bool, options validation.ListOptions) ([]models.StudyActivity, models.PageInfo, error)
+	GetStudyActivity(id int64) (*models.StudyActivity, error)
+	GetStudyActivitySessions(activityID int64, options validation.ListOptions) ([]models.StudySession, models.PageInfo, error)
+	CreateStudyActivity(activity models.StudyActivity) (*models.StudyActivity, error)
+	UpdateStudyActivity(id int64, update models.StudyActivityUpdate) (*models.StudyActivity, error)
+	DeleteStudyActivity(id int64) (bool, error)
+
+	LaunchStudyActivity(activityID, groupID int64) (*models.StudyLaunch, error)
+	ValidateSessionToken(sessionID int64, token string) error
+}
+
+// ImportStore moves vocabulary in and out of other study tools
+type ImportStore interface {
+	ImportWords(imports []models.GroupImport) ([]models.ImportResult, error)
+	ExportGroup(id int64) (*models.GroupExport, error)
+	ExportBackup() (*models.Backup, error)
+	RestoreBackup(backup *models.Backup) (*models.RestoreResult, error)
+}
+
+// ReviewStore records reviews and schedules words for review
+type ReviewStore interface {
+	AddWordReview(review models.WordReviewItem) (*models.WordReviewItem, error)
+	AddWordReviews(studySessionID int64, reviews []models.WordReviewItem) ([]models.ReviewResult, error)
+	GetDueWords(groupID int64, options validation.ListOptions) ([]models.DueWord, models.PageInfo, error)
+}
+
+// StatsStore provides the dashboard statistics
+type StatsStore interface {
+	GetLastStudySession() (*models.StudySession, error)
+	GetStudyProgress() (*models.StudyProgress, error)
+	GetQuickStats() (*models.QuickStats, error)
+}
+
+// Store is the storage backend behind the API. Methods follow the same
+// conventions in every implementation: lookups return nil when the resource
+// does not exist and failures caused by the request are *Error values.
+type Store interface {
+	WordStore
+	GroupStore
+	SessionStore
+	ReviewStore
+	StatsStore
+	ImportStore
+
+	// ResetHistory deletes all study sessions, reviews and review schedules
+	ResetHistory() error
+	// FullReset replaces all data with the seed data
+	FullReset() error
+	// SupportsSnapshots reports whether the store is a SQLite database that Snapshot can copy
+	SupportsSnapshots() bool
+	// Snapshot writes a consistent copy of a SQLite database to path while it is in use
+	Snapshot(path string) error
+	Close() error
+}
+
+var (
+	_ Store = (*DBService)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryDSN selects the in-memory store, which starts out with the seed data
+// and loses every change when the process exits
+const MemoryDSN = "memory:"
+
+// OpenStore opens the store selected by a DSN: MemoryDSN for an in-memory
+// store, otherwise a SQLite file path or PostgreSQL URL as for NewDBService.
+// A database store must have every migration applied, see CheckSchema.
+func OpenStore(dsn string) (Store, error) {
+	if dsn == MemoryDSN {
+		store := NewMemoryStore()
+		if err := store.FullReset(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	db, err := NewDBService(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSchema(db.db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
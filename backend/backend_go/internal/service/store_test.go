@@ -0,0 +1,502 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"pengyou-chinese/backend/internal/models"
+	"pengyou-chinese/backend/internal/validation"
+)
+
+// storeScenario exercises a Store that starts out with the seed data. Besides
+// checking the store's behaviour it records what it observed in a trace, which
+// must be the same for every implementation.
+type storeScenario struct {
+	name string
+	run  func(t *testing.T, s Store, trace *storeTrace)
+}
+
+// storeTrace records the results of store calls, leaving out timestamps,
+// which differ from run to run
+type storeTrace []string
+
+func (tr *storeTrace) add(label string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	*tr = append(*tr, label+": "+string(data))
+}
+
+// addErr records an error by its message, or "ok" for no error
+func (tr *storeTrace) addErr(label string, err error) {
+	message := "ok"
+	if err != nil {
+		message = err.Error()
+	}
+	*tr = append(*tr, label+": "+message)
+}
+
+// storeOpeners open a seeded store of each implementation tested without
+// external services
+var storeOpeners = map[string]func(t *testing.T) Store{
+	"memory": openMemoryStore,
+	"sqlite": openSQLiteStore,
+}
+
+func openMemoryStore(t *testing.T) Store {
+	t.Helper()
+	store := NewMemoryStore()
+	if err := store.FullReset(); err != nil {
+		t.Fatalf("seeding memory store: %v", err)
+	}
+	return store
+}
+
+func openSQLiteStore(t *testing.T) Store {
+	t.Helper()
+	return openDBStore(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// openDBStore opens the database selected by dsn and replaces its data with
+// the seed data
+func openDBStore(t *testing.T, dsn string) Store {
+	t.Helper()
+	db, err := NewDBService(dsn)
+	if err != nil {
+		t.Fatalf("opening %s: %v", dsn, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.FullReset(); err != nil {
+		t.Fatalf("seeding %s: %v", dsn, err)
+	}
+	return db
+}
+
+// runStoreScenarios runs every scenario against a fresh store from open and
+// returns the traces by scenario name
+func runStoreScenarios(t *testing.T, open func(t *testing.T) Store) map[string]storeTrace {
+	traces := make(map[string]storeTrace)
+	for _, scenario := range storeScenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			var trace storeTrace
+			scenario.run(t, open(t), &trace)
+			traces[scenario.name] = trace
+		})
+	}
+	return traces
+}
+
+func TestStores(t *testing.T) {
+	for name, open := range storeOpeners {
+		t.Run(name, func(t *testing.T) {
+			runStoreScenarios(t, open)
+		})
+	}
+}
+
+// TestStoreParity checks that the memory store behaves exactly like the
+// SQLite database it stands in for
+func TestStoreParity(t *testing.T) {
+	want := runStoreScenarios(t, openSQLiteStore)
+	got := runStoreScenarios(t, openMemoryStore)
+	compareTraces(t, "sqlite", want, "memory", got)
+}
+
+// compareTraces reports the first step of each scenario where the traces of
+// two stores differ. Traces of scenarios that already failed are incomplete
+// and not compared.
+func compareTraces(t *testing.T, wantName string, want map[string]storeTrace, gotName string, got map[string]storeTrace) {
+	t.Helper()
+	if t.Failed() {
+		return
+	}
+	for _, scenario := range storeScenarios {
+		w, g := want[scenario.name], got[scenario.name]
+		for i := 0; i < len(w) || i < len(g); i++ {
+			var wantLine, gotLine string
+			if i < len(w) {
+				wantLine = w[i]
+			}
+			if i < len(g) {
+				gotLine = g[i]
+			}
+			if wantLine != gotLine {
+				t.Errorf("%s: %s store differs from %s at step %d\n %s: %s\n %s: %s",
+					scenario.name, gotName, wantName, i, wantName, wantLine, gotName, gotLine)
+				break
+			}
+		}
+	}
+}
+
+// firstPage lists the first page of up to pageSize rows in the default order
+func firstPage(pageSize int) validation.ListOptions {
+	return validation.ListOptions{Page: 1, PageSize: pageSize}
+}
+
+// wordIDs returns the IDs of a list of words
+func wordIDs(words []models.WordWithStats) []int64 {
+	ids := make([]int64, len(words))
+	for i, word := range words {
+		ids[i] = word.ID
+	}
+	return ids
+}
+
+// sessionSummary is the part of a study session that does not depend on the clock
+type sessionSummary struct {
+	ID               int64
+	GroupID          int64
+	StudyActivityID  int64
+	Status           string
+	ReviewItemsCount int
+	Ended            bool
+}
+
+func summarizeSession(session *models.StudySession) *sessionSummary {
+	if session == nil {
+		return nil
+	}
+	return &sessionSummary{
+		ID:               session.ID,
+		GroupID:          session.GroupID,
+		StudyActivityID:  session.StudyActivityID,
+		Status:           session.Status,
+		ReviewItemsCount: session.ReviewItemsCount,
+		Ended:            session.EndTime != nil,
+	}
+}
+
+func mustNoErr(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func wantErr(t *testing.T, what string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("%s: got error %v, want %v", what, err, target)
+	}
+}
+
+var storeScenarios = []storeScenario{
+	{"word CRUD", func(t *testing.T, s Store, trace *storeTrace) {
+		created, err := s.CreateWord(models.Word{Japanese: "ありがとう", Romaji: "arigatou", English: "thank you", Parts: `{"type":"expression"}`}, []int64{1})
+		mustNoErr(t, "creating word", err)
+		trace.add("created", created.Word)
+		trace.add("created groups", created.Groups)
+
+		word, err := s.GetWord(created.ID)
+		mustNoErr(t, "getting word", err)
+		if word == nil || word.English != "thank you" {
+			t.Fatalf("got word %+v, want the created word", word)
+		}
+
+		english := "thanks"
+		groupIDs := []int64{2}
+		updated, err := s.UpdateWord(created.ID, models.WordUpdate{English: &english, GroupIDs: &groupIDs})
+		mustNoErr(t, "updating word", err)
+		if updated.English != "thanks" || updated.Romaji != "arigatou" {
+			t.Fatalf("got updated word %+v, want only english changed", updated.Word)
+		}
+		trace.add("updated", updated.Word)
+		trace.add("updated groups", updated.Groups)
+
+		_, err = s.CreateWord(models.Word{Japanese: "はい", Romaji: "hai", English: "yes"}, []int64{99})
+		wantErr(t, "creating word in a missing group", err, ErrGroupNotFound)
+		trace.addErr("missing group", err)
+
+		deleted, err := s.DeleteWord(created.ID)
+		mustNoErr(t, "deleting word", err)
+		if !deleted {
+			t.Fatal("deleting word: got false, want true")
+		}
+		word, err = s.GetWord(created.ID)
+		mustNoErr(t, "getting deleted word", err)
+		if word != nil {
+			t.Fatalf("got deleted word %+v, want nil", word)
+		}
+		deleted, err = s.DeleteWord(created.ID)
+		mustNoErr(t, "deleting word again", err)
+		trace.add("deleted again", deleted)
+	}},
+
+	{"groups", func(t *testing.T, s Store, trace *storeTrace) {
+		group, err := s.CreateGroup("Food")
+		mustNoErr(t, "creating group", err)
+		trace.add("created", group)
+
+		added, err := s.AddWordsToGroup(group.ID, []int64{1, 2, 3})
+		mustNoErr(t, "adding words", err)
+		trace.add("added", added)
+		added, err = s.AddWordsToGroup(group.ID, []int64{3})
+		mustNoErr(t, "adding words again", err)
+		if added != 0 {
+			t.Fatalf("adding a member again: got %d added, want 0", added)
+		}
+		_, err = s.AddWordsToGroup(group.ID, []int64{999})
+		wantErr(t, "adding a missing word", err, ErrWordNotFound)
+		trace.addErr("missing word", err)
+
+		removed, err := s.RemoveWordsFromGroup(group.ID, []int64{1, 4})
+		mustNoErr(t, "removing words", err)
+		trace.add("removed", removed)
+
+		renamed, err := s.RenameGroup(group.ID, "Food and Drink")
+		mustNoErr(t, "renaming group", err)
+		trace.add("renamed", renamed)
+
+		words, _, err := s.GetGroupWords(group.ID, firstPage(100))
+		mustNoErr(t, "listing group words", err)
+		trace.add("members", wordIDs(words))
+
+		groups, info, err := s.GetGroups(validation.ListOptions{Page: 1, PageSize: 10, SortBy: "word_count", Desc: true})
+		mustNoErr(t, "listing groups", err)
+		trace.add("groups", groups)
+		trace.add("groups page", info)
+
+		deleted, err := s.DeleteGroup(group.ID)
+		mustNoErr(t, "deleting group", err)
+		trace.add("deleted", deleted)
+		missing, err := s.GetGroup(group.ID)
+		mustNoErr(t, "getting deleted group", err)
+		if missing != nil {
+			t.Fatalf("got deleted group %+v, want nil", missing)
+		}
+	}},
+
+	{"word lists", func(t *testing.T, s Store, trace *storeTrace) {
+		words, info, err := s.GetWords(firstPage(4))
+		mustNoErr(t, "listing words", err)
+		if info.TotalItems != 10 || len(words) != 4 {
+			t.Fatalf("got %d of %d words, want 4 of 10", len(words), info.TotalItems)
+		}
+		trace.add("first page", words)
+		trace.add("first page info", info)
+
+		words, info, err = s.GetWords(validation.ListOptions{Page: 3, PageSize: 4})
+		mustNoErr(t, "listing last page", err)
+		trace.add("last page", wordIDs(words))
+		trace.add("last page info", info)
+
+		words, _, err = s.GetWords(validation.ListOptions{Page: 1, PageSize: 100, SortBy: "english", Desc: true})
+		mustNoErr(t, "sorting words", err)
+		trace.add("by english desc", wordIDs(words))
+
+		words, _, err = s.GetWords(validation.ListOptions{Page: 1, PageSize: 100, SortBy: "romaji", Filters: []validation.Filter{
+			{Field: "english", Op: validation.OpContains, Value: "O"},
+			{Field: "id", Op: validation.OpGt, Value: float64(1)},
+		}})
+		mustNoErr(t, "filtering words", err)
+		trace.add("filtered", wordIDs(words))
+
+		words, _, err = s.GetGroupWords(2, validation.ListOptions{Page: 1, PageSize: 100, SortBy: "japanese"})
+		mustNoErr(t, "listing group words", err)
+		trace.add("group words", wordIDs(words))
+	}},
+
+	{"cursor pagination", func(t *testing.T, s Store, trace *storeTrace) {
+		for _, sort := range []struct {
+			by   string
+			desc bool
+		}{{"", false}, {"romaji", false}, {"english", true}} {
+			all, _, err := s.GetWords(validation.ListOptions{Page: 1, PageSize: 100, SortBy: sort.by, Desc: sort.desc})
+			mustNoErr(t, "listing words", err)
+
+			// Page sizes that do and do not divide the number of words, so
+			// that the last page is full once
+			for _, pageSize := range []int{3, len(all) / 2} {
+				var paged []int64
+				options := validation.ListOptions{PageSize: pageSize, SortBy: sort.by, Desc: sort.desc, CursorMode: true}
+				for {
+					words, info, err := s.GetWords(options)
+					mustNoErr(t, "listing words by cursor", err)
+					if len(words) == 0 {
+						t.Fatalf("sort %q: a cursor led to an empty page of %d", sort.by, pageSize)
+					}
+					paged = append(paged, wordIDs(words)...)
+					if info.NextCursor == "" {
+						break
+					}
+					if len(paged) > len(all) {
+						t.Fatalf("sort %q: cursor pages go past the %d words", sort.by, len(all))
+					}
+					options.After, err = validation.DecodeCursor(info.NextCursor)
+					mustNoErr(t, "decoding cursor", err)
+				}
+
+				if want := wordIDs(all); !slices.Equal(paged, want) {
+					t.Fatalf("sort %q: cursor pages of %d gave %v, want %v", sort.by, pageSize, paged, want)
+				}
+				trace.add("pages by "+sort.by+" of "+strconv.Itoa(pageSize), paged)
+			}
+		}
+	}},
+
+	{"search", func(t *testing.T, s Store, trace *storeTrace) {
+		for _, q := range []string{"hello", "konnichiwa", "good", "none-such"} {
+			words, _, err := s.SearchWords(q, firstPage(100))
+			mustNoErr(t, "searching "+q, err)
+			// bm25 also ranks by text length, so only the matches are compared
+			ids := wordIDs(words)
+			slices.Sort(ids)
+			trace.add("search "+q, ids)
+		}
+		words, _, err := s.SearchWords("hello", firstPage(100))
+		mustNoErr(t, "searching hello", err)
+		if len(words) == 0 || words[0].English != "hello" {
+			t.Fatalf("searching hello: got %+v, want hello first", words)
+		}
+	}},
+
+	{"reviews", func(t *testing.T, s Store, trace *storeTrace) {
+		session, err := s.CreateStudySession(1, 1)
+		mustNoErr(t, "creating session", err)
+		trace.add("session", summarizeSession(session))
+
+		_, err = s.CreateStudySession(99, 1)
+		wantErr(t, "creating session for a missing group", err, ErrGroupNotFound)
+		trace.addErr("missing group", err)
+
+		review, err := s.AddWordReview(models.WordReviewItem{WordID: 1, StudySessionID: session.ID, Correct: true})
+		mustNoErr(t, "adding review", err)
+		trace.add("review", review.ID)
+
+		_, err = s.AddWordReview(models.WordReviewItem{WordID: 6, StudySessionID: session.ID})
+		wantErr(t, "reviewing a word outside the group", err, ErrWordNotInGroup)
+		trace.addErr("outside group", err)
+
+		now := time.Now().UTC()
+		results, err := s.AddWordReviews(session.ID, []models.WordReviewItem{
+			{WordID: 2, Correct: false, CreatedAt: now},
+			{WordID: 999, Correct: true, CreatedAt: now},
+			{WordID: 2, Correct: true, CreatedAt: now},
+			{WordID: 6, Correct: true, CreatedAt: now},
+		})
+		mustNoErr(t, "adding batch", err)
+		trace.add("batch", results)
+		if !results[0].Success || results[1].Success || !results[2].Success || results[3].Success {
+			t.Fatalf("got batch results %+v, want the reviews of words 2 to succeed", results)
+		}
+
+		_, err = s.AddWordReviews(session.ID, []models.WordReviewItem{{WordID: 1, CreatedAt: now.Add(time.Hour)}})
+		wantErr(t, "adding a review answered in the future", err, ErrAnswerTimeOutOfRange)
+		trace.addErr("future", err)
+		_, err = s.AddWordReviews(session.ID, []models.WordReviewItem{{WordID: 1, CreatedAt: now.Add(-time.Hour)}})
+		wantErr(t, "adding a review answered before the session", err, ErrAnswerTimeOutOfRange)
+		trace.addErr("before session", err)
+
+		words, _, err := s.GetStudySessionWords(session.ID, firstPage(100))
+		mustNoErr(t, "listing session words", err)
+		for _, word := range words {
+			trace.add("session word", []any{word.ID, word.CorrectCount, word.WrongCount, len(word.Reviews)})
+		}
+
+		due, _, err := s.GetDueWords(1, firstPage(100))
+		mustNoErr(t, "listing due words", err)
+		for _, word := range due {
+			trace.add("due", []any{word.ID, word.Schedule != nil})
+		}
+
+		finished, err := s.FinishStudySession(session.ID)
+		mustNoErr(t, "finishing session", err)
+		if finished.Status != models.SessionStatusFinished {
+			t.Fatalf("got status %q after finishing, want finished", finished.Status)
+		}
+		trace.add("finished", summarizeSession(finished))
+
+		_, err = s.FinishStudySession(session.ID)
+		wantErr(t, "finishing session again", err, ErrSessionNotActive)
+		_, err = s.AddWordReview(models.WordReviewItem{WordID: 1, StudySessionID: session.ID})
+		wantErr(t, "reviewing in a finished session", err, ErrSessionNotActive)
+		_, err = s.AddWordReviews(session.ID, []models.WordReviewItem{{WordID: 1, CreatedAt: now}})
+		wantErr(t, "adding a batch to a finished session", err, ErrSessionNotActive)
+		trace.addErr("finished session", err)
+
+		stats, err := s.GetQuickStats()
+		mustNoErr(t, "getting quick stats", err)
+		trace.add("quick stats", []any{stats.TotalStudySessions, stats.TotalActiveGroups, stats.SuccessRate})
+	}},
+
+	{"backup restore", func(t *testing.T, s Store, trace *storeTrace) {
+		// Two reviews of a word answered in the same second, in two sessions
+		// of the same group and activity started in the same second
+		now := time.Now().UTC().Truncate(time.Second)
+		for range 2 {
+			session, err := s.CreateStudySession(1, 1)
+			mustNoErr(t, "creating session", err)
+			_, err = s.AddWordReviews(session.ID, []models.WordReviewItem{
+				{WordID: 1, Correct: true, CreatedAt: now},
+				{WordID: 1, Correct: false, CreatedAt: now},
+			})
+			mustNoErr(t, "adding reviews", err)
+		}
+
+		backup, err := s.ExportBackup()
+		mustNoErr(t, "exporting backup", err)
+		if backup.ID == "" {
+			t.Fatal("exported backup has no ID")
+		}
+		mustNoErr(t, "resetting", s.FullReset())
+
+		result, err := s.RestoreBackup(backup)
+		mustNoErr(t, "restoring backup", err)
+		want := models.RestoreCounts{Created: 2}
+		if result.StudySessions != want {
+			t.Fatalf("got restored sessions %+v, want %+v", result.StudySessions, want)
+		}
+		if want := (models.RestoreCounts{Created: 4}); result.Reviews != want {
+			t.Fatalf("got restored reviews %+v, want %+v", result.Reviews, want)
+		}
+		trace.add("restored", result)
+
+		result, err = s.RestoreBackup(backup)
+		mustNoErr(t, "restoring backup again", err)
+		if result.StudySessions.Created != 0 || result.Reviews.Created != 0 {
+			t.Fatalf("restoring the same archive again added %+v", result)
+		}
+		trace.add("restored again", result)
+
+		// Archives from before backups had IDs are told apart by their contents
+		backup.ID = ""
+		result, err = s.RestoreBackup(backup)
+		mustNoErr(t, "restoring backup without ID", err)
+		trace.add("restored without ID", result)
+		result, err = s.RestoreBackup(backup)
+		mustNoErr(t, "restoring backup without ID again", err)
+		if result.Reviews.Created != 0 {
+			t.Fatalf("restoring the same archive without ID again added %+v", result)
+		}
+
+		stats, err := s.GetQuickStats()
+		mustNoErr(t, "getting quick stats", err)
+		trace.add("sessions", stats.TotalStudySessions)
+	}},
+
+	{"launch tokens", func(t *testing.T, s Store, trace *storeTrace) {
+		launch, err := s.LaunchStudyActivity(1, 1)
+		mustNoErr(t, "launching activity", err)
+		trace.add("launched", summarizeSession(&launch.StudySession))
+		sessionID := launch.StudySession.ID
+
+		wantErr(t, "using a launched session without a token", s.ValidateSessionToken(sessionID, ""), ErrSessionTokenRequired)
+		wantErr(t, "using a launched session with a wrong token", s.ValidateSessionToken(sessionID, "wrong"), ErrInvalidSessionToken)
+		mustNoErr(t, "using a launched session with its token", s.ValidateSessionToken(sessionID, launch.Token))
+
+		session, err := s.CreateStudySession(1, 1)
+		mustNoErr(t, "creating session", err)
+		mustNoErr(t, "using a plain session without a token", s.ValidateSessionToken(session.ID, ""))
+		wantErr(t, "using another session's token", s.ValidateSessionToken(session.ID, launch.Token), ErrInvalidSessionToken)
+
+		_, err = s.LaunchStudyActivity(99, 1)
+		trace.addErr("missing activity", err)
+	}},
+}
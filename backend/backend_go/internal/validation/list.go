@@ -0,0 +1,308 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldKind is the type of value a list field holds
+type FieldKind int
+
+const (
+	StringField FieldKind = iota
+	NumberField
+	TimeField
+)
+
+// Filter operators
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpContains = "contains"
+)
+
+// ListFields whitelists the fields a list can be sorted and filtered by.
+// Aliases map alternative names onto whitelisted fields.
+type ListFields struct {
+	Kinds   map[string]FieldKind
+	Aliases map[string]string
+}
+
+// Whitelisted fields for each list endpoint
+var (
+	WordListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":              NumberField,
+			"japanese":        StringField,
+			"romaji":          StringField,
+			"english":         StringField,
+			"correct_count":   NumberField,
+			"wrong_count":     NumberField,
+			"last_studied_at": TimeField,
+		},
+		Aliases: map[string]string{"kanji": "japanese", "last_studied": "last_studied_at"},
+	}
+	DueWordListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":       NumberField,
+			"japanese": StringField,
+			"romaji":   StringField,
+			"english":  StringField,
+		},
+		Aliases: map[string]string{"kanji": "japanese"},
+	}
+	GroupListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":         NumberField,
+			"name":       StringField,
+			"word_count": NumberField,
+		},
+		Aliases: map[string]string{"group_name": "name"},
+	}
+	StudySessionListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":                 NumberField,
+			"group_id":           NumberField,
+			"group_name":         StringField,
+			"study_activity_id":  NumberField,
+			"activity_name":      StringField,
+			"status":             StringField,
+			"created_at":         TimeField,
+			"ended_at":           TimeField,
+			"review_items_count": NumberField,
+		},
+		Aliases: map[string]string{"start_time": "created_at", "end_time": "ended_at"},
+	}
+	StudyActivityListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":         NumberField,
+			"name":       StringField,
+			"enabled":    NumberField,
+			"created_at": TimeField,
+		},
+	}
+	WordReviewListFields = ListFields{
+		Kinds: map[string]FieldKind{
+			"id":                NumberField,
+			"study_session_id":  NumberField,
+			"correct":           NumberField,
+			"response_ms":       NumberField,
+			"hint_used":         NumberField,
+			"grade":             NumberField,
+			"created_at":        TimeField,
+			"group_id":          NumberField,
+			"study_activity_id": NumberField,
+		},
+	}
+)
+
+// ListRequest represents the pagination, sort, filter and cursor query parameters shared by list endpoints.
+// Filters have the form field:op:value, e.g. wrong_count:gt:correct_count.
+// An empty cursor requests the first page in cursor mode.
+type ListRequest struct {
+	PaginationRequest
+	SortBy  string   `form:"sort_by"`
+	Order   string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	Filters []string `form:"filter"`
+	Cursor  *string  `form:"cursor"`
+}
+
+// ListOptions is a parsed ListRequest containing only whitelisted fields.
+// In cursor mode the list continues after the row identified by After, or
+// starts from the beginning when After is nil.
+type ListOptions struct {
+	Page       int
+	PageSize   int
+	SortBy     string
+	Desc       bool
+	Filters    []Filter
+	CursorMode bool
+	After      *Cursor
+}
+
+// Cursor identifies the last row of a page by its sort value and ID.
+// An empty SortBy refers to the list's default order.
+type Cursor struct {
+	SortBy string `json:"s,omitempty"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  any    `json:"v"`
+	ID     int64  `json:"id"`
+}
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or do not match the list
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// FieldError reports an invalid value for a single request field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor Cursor
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// Keep integers exact instead of decoding every number as float64
+	if n, ok := cursor.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			cursor.Value = i
+		} else if f, err := n.Float64(); err == nil {
+			cursor.Value = f
+		} else {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return &cursor, nil
+}
+
+// Filter restricts a list to rows where Field compares to Value, or to the
+// field named by ValueField when comparing two numeric fields
+type Filter struct {
+	Field      string
+	Op         string
+	Value      any
+	ValueField string
+}
+
+// ParseListOptions validates a ListRequest against the whitelisted fields
+func ParseListOptions(request ListRequest, fields ListFields) (ListOptions, error) {
+	var options ListOptions
+	options.Page, options.PageSize = GetDefaultPagination(request.Page, request.PageSize)
+
+	if request.SortBy != "" {
+		field, ok := fields.lookup(request.SortBy)
+		if !ok {
+			return options, &FieldError{Field: "sort_by", Message: fmt.Sprintf("cannot sort by %q", request.SortBy)}
+		}
+		options.SortBy = field
+	}
+	options.Desc = request.Order == "desc"
+
+	if request.Cursor != nil {
+		options.CursorMode = true
+		if *request.Cursor != "" {
+			cursor, err := DecodeCursor(*request.Cursor)
+			if err != nil {
+				return options, &FieldError{Field: "cursor", Message: err.Error()}
+			}
+			if cursor.SortBy != "" {
+				if _, ok := fields.Kinds[cursor.SortBy]; !ok {
+					return options, &FieldError{Field: "cursor", Message: ErrInvalidCursor.Error()}
+				}
+			}
+
+			// The cursor carries the sort of the list it was issued for
+			if request.SortBy == "" && request.Order == "" {
+				options.SortBy, options.Desc = cursor.SortBy, cursor.Desc
+			} else if options.SortBy != cursor.SortBy || options.Desc != cursor.Desc {
+				return options, &FieldError{Field: "cursor", Message: "cursor was issued for a different sort order"}
+			}
+			options.After = cursor
+		}
+	}
+
+	for _, raw := range request.Filters {
+		filter, err := fields.parseFilter(raw)
+		if err != nil {
+			return options, &FieldError{Field: "filter", Message: err.Error()}
+		}
+		options.Filters = append(options.Filters, filter)
+	}
+
+	return options, nil
+}
+
+// lookup resolves a field name or alias to a whitelisted field
+func (f ListFields) lookup(name string) (string, bool) {
+	if alias, ok := f.Aliases[name]; ok {
+		name = alias
+	}
+	_, ok := f.Kinds[name]
+	return name, ok
+}
+
+// parseFilter parses a field:op:value filter
+func (f ListFields) parseFilter(raw string) (Filter, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 {
+		return Filter{}, fmt.Errorf("invalid filter %q, expected field:op:value", raw)
+	}
+
+	field, ok := f.lookup(parts[0])
+	if !ok {
+		return Filter{}, fmt.Errorf("cannot filter by %q", parts[0])
+	}
+	filter := Filter{Field: field, Op: parts[1]}
+	value := parts[2]
+	kind := f.Kinds[field]
+
+	switch filter.Op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+	case OpContains:
+		if kind != StringField {
+			return Filter{}, fmt.Errorf("filter %q: contains only applies to text fields", raw)
+		}
+	default:
+		return Filter{}, fmt.Errorf("filter %q: unknown operator %q", raw, filter.Op)
+	}
+
+	switch kind {
+	case StringField:
+		filter.Value = value
+	case NumberField:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			filter.Value = n
+		} else if other, ok := f.lookup(value); ok && f.Kinds[other] == NumberField {
+			filter.ValueField = other
+		} else {
+			return Filter{}, fmt.Errorf("filter %q: %q is not a number or numeric field", raw, value)
+		}
+	case TimeField:
+		t, err := parseFilterTime(value)
+		if err != nil {
+			return Filter{}, fmt.Errorf("filter %q: %q is not a date or RFC 3339 time", raw, value)
+		}
+		filter.Value = t
+	}
+
+	return filter, nil
+}
+
+// parseFilterTime accepts RFC 3339 times and plain dates
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -1,29 +1,275 @@
 package validation
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // CreateStudySessionRequest represents the request to create a study session
 type CreateStudySessionRequest struct {
 	GroupID         int64 `json:"group_id" binding:"required,min=1"`
 	StudyActivityID int64 `json:"study_activity_id" binding:"required,min=1"`
 }
 
+// LaunchStudyActivityRequest represents the request to launch a study activity for a group
+type LaunchStudyActivityRequest struct {
+	GroupID int64 `json:"group_id" binding:"required,min=1"`
+}
+
 // AddWordReviewRequest represents the request to add a word review
 type AddWordReviewRequest struct {
-	Correct bool `json:"correct" binding:"required"`
+	Correct        *bool  `json:"correct" binding:"required"`
+	Answer         string `json:"answer" binding:"max=1000"`
+	ExpectedAnswer string `json:"expected_answer" binding:"max=1000"`
+	ResponseMS     *int   `json:"response_ms" binding:"omitempty,min=0"`
+	HintUsed       bool   `json:"hint_used"`
+	Grade          *int   `json:"grade" binding:"omitempty,min=0,max=5"`
+}
+
+// CreateWordRequest represents the request to create a word
+type CreateWordRequest struct {
+	Japanese string  `json:"japanese" binding:"required"`
+	Romaji   string  `json:"romaji" binding:"required"`
+	English  string  `json:"english" binding:"required"`
+	Parts    string  `json:"parts" binding:"omitempty,json"`
+	GroupIDs []int64 `json:"group_ids" binding:"omitempty,dive,min=1"`
+}
+
+// UpdateWordRequest represents the request to replace a word.
+// The word's groups are only replaced when group_ids is present.
+type UpdateWordRequest struct {
+	Japanese string   `json:"japanese" binding:"required"`
+	Romaji   string   `json:"romaji" binding:"required"`
+	English  string   `json:"english" binding:"required"`
+	Parts    string   `json:"parts" binding:"omitempty,json"`
+	GroupIDs *[]int64 `json:"group_ids" binding:"omitempty,dive,min=1"`
+}
+
+// PatchWordRequest represents the request to partially update a word
+type PatchWordRequest struct {
+	Japanese *string  `json:"japanese" binding:"omitempty,min=1"`
+	Romaji   *string  `json:"romaji" binding:"omitempty,min=1"`
+	English  *string  `json:"english" binding:"omitempty,min=1"`
+	Parts    *string  `json:"parts" binding:"omitempty,json"`
+	GroupIDs *[]int64 `json:"group_ids" binding:"omitempty,dive,min=1"`
+}
+
+// GroupRequest represents the request to create or rename a group
+type GroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// GroupWordsRequest represents the request to add or remove words from a group
+type GroupWordsRequest struct {
+	WordIDs []int64 `json:"word_ids" binding:"required,min=1,dive,min=1"`
+}
+
+// StudyActivitiesRequest represents the query parameters for listing study activities
+type StudyActivitiesRequest struct {
+	EnabledOnly bool `form:"enabled_only"`
+}
+
+// StudyActivityRequest represents the request to create or replace a study activity.
+// Activities are enabled unless enabled is explicitly false.
+type StudyActivityRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	ThumbnailURL string `json:"thumbnail_url" binding:"omitempty,url"`
+	LaunchURL    string `json:"launch_url" binding:"required,url"`
+	Enabled      *bool  `json:"enabled"`
+}
+
+// PatchStudyActivityRequest represents the request to partially update a study activity
+type PatchStudyActivityRequest struct {
+	Name         *string `json:"name" binding:"omitempty,min=1"`
+	Description  *string `json:"description"`
+	ThumbnailURL *string `json:"thumbnail_url" binding:"omitempty,url"`
+	LaunchURL    *string `json:"launch_url" binding:"omitempty,url"`
+	Enabled      *bool   `json:"enabled"`
+}
+
+// Confirmation tokens that must be sent to the destructive reset endpoints
+const (
+	ResetHistoryConfirmation = "RESET_HISTORY"
+	FullResetConfirmation    = "FULL_RESET"
+)
+
+// ResetHistoryRequest represents the request to wipe all study history
+type ResetHistoryRequest struct {
+	Confirm string `json:"confirm" binding:"required,eq=RESET_HISTORY"`
+}
+
+// FullResetRequest represents the request to drop and re-seed the whole database
+type FullResetRequest struct {
+	Confirm string `json:"confirm" binding:"required,eq=FULL_RESET"`
+}
+
+// BatchReviewItem represents a single review in a batch submission.
+// answered_at defaults to the time the batch is received.
+type BatchReviewItem struct {
+	AddWordReviewRequest
+	WordID     int64      `json:"word_id" binding:"required,min=1"`
+	AnsweredAt *time.Time `json:"answered_at"`
+}
+
+// BatchReviewRequest represents the request to submit several reviews at once
+type BatchReviewRequest struct {
+	Reviews []BatchReviewItem `json:"reviews" binding:"required,min=1,max=500,dive"`
+}
+
+// SearchWordsRequest represents the query parameters of a word search
+type SearchWordsRequest struct {
+	Q string `form:"q" binding:"required,max=200"`
 }
 
-// PaginationRequest represents common pagination parameters
+// PaginationRequest represents common pagination parameters. They are
+// pointers so that an explicit 0 is rejected rather than taken for an omitted
+// value; omitted values fall back to GetDefaultPagination.
 type PaginationRequest struct {
-	Page     int `form:"page" binding:"min=1"`
-	PageSize int `form:"page_size" binding:"min=1,max=100"`
+	Page     *int `form:"page" binding:"omitempty,min=1"`
+	PageSize *int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// GetDefaultPagination returns the pagination values, with the defaults for those not provided
+func GetDefaultPagination(page, pageSize *int) (int, int) {
+	p, size := 1, 100
+	if page != nil && *page >= 1 {
+		p = *page
+	}
+	if pageSize != nil && *pageSize >= 1 && *pageSize <= 100 {
+		size = *pageSize
+	}
+	return p, size
+}
+
+// DueReviewsRequest represents the query parameters for listing words due for review
+type DueReviewsRequest struct {
+	GroupID int64 `form:"group_id" binding:"omitempty,min=1"`
 }
 
-// GetDefaultPagination returns default pagination values if not provided
-func GetDefaultPagination(page, pageSize int) (int, int) {
-	if page < 1 {
-		page = 1
+// ImportRequest represents the form fields sent along with an uploaded vocabulary
+// file. The words go into the existing group group_id or the group named
+// group_name, which is created if there is none and defaults to the group name
+// of a JSON seed file. The format
+// defaults to the file's extension. The *_column fields map the columns of a
+// CSV or TSV file by header name or 1-based position; header=false means the
+// file has no header row.
+type ImportRequest struct {
+	Format         string `form:"format" binding:"omitempty,oneof=csv tsv json"`
+	GroupID        int64  `form:"group_id" binding:"omitempty,min=1"`
+	GroupName      string `form:"group_name" binding:"max=200"`
+	Header         *bool  `form:"header"`
+	JapaneseColumn string `form:"japanese_column"`
+	RomajiColumn   string `form:"romaji_column"`
+	EnglishColumn  string `form:"english_column"`
+	PartsColumn    string `form:"parts_column"`
+}
+
+// AnkiImportRequest represents the form fields sent along with an uploaded
+// Anki package. The notes go into the existing group group_id, or else into a
+// group per deck named after the deck. The *_field fields map the fields of
+// the notes by name or 1-based position; skip_invalid=true imports the valid
+// notes instead of rejecting the package when some are invalid.
+type AnkiImportRequest struct {
+	GroupID       int64  `form:"group_id" binding:"omitempty,min=1"`
+	JapaneseField string `form:"japanese_field"`
+	RomajiField   string `form:"romaji_field"`
+	EnglishField  string `form:"english_field"`
+	PartsField    string `form:"parts_field"`
+	ReadingField  string `form:"reading_field"`
+	SkipInvalid   bool   `form:"skip_invalid"`
+}
+
+// RowError reports an invalid value in an uploaded file, located by its line
+// number, or by Field alone in files without meaningful lines such as JSON
+type RowError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+// RowErrors reports every invalid value found in an uploaded file
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	if len(e) == 0 {
+		return "no errors"
+	}
+	first := e[0]
+	message := first.Field + ": " + first.Message
+	if first.Line > 0 {
+		message = fmt.Sprintf("line %d: %s", first.Line, message)
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 100
+	if len(e) > 1 {
+		message += fmt.Sprintf(" (and %d more errors)", len(e)-1)
+	}
+	return message
+}
+
+// ParamError names the query or form parameter of obj behind a number or
+// boolean that failed to parse, which gin's binding reports as a bare
+// *strconv.NumError. The parameter is the numeric or boolean field of obj
+// whose value in values is the one that failed. Other errors are returned as
+// they are.
+func ParamError(obj any, values url.Values, err error) error {
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		return err
+	}
+	for _, name := range parsedParams(reflect.TypeOf(obj)) {
+		if slices.Contains(values[name], numErr.Num) {
+			return &FieldError{Field: name, Message: ParseErrorMessage(numErr)}
+		}
+	}
+	return err
+}
+
+// ParseErrorMessage describes a number or boolean that failed to parse
+func ParseErrorMessage(err *strconv.NumError) string {
+	if err.Func == "ParseBool" {
+		return fmt.Sprintf("%q is not a valid boolean", err.Num)
+	}
+	return fmt.Sprintf("%q is not a valid number", err.Num)
+}
+
+// parsedParams returns the form names of the numeric and boolean fields of a
+// request struct, including those of embedded structs
+func parsedParams(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			names = append(names, parsedParams(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		kind := field.Type.Kind()
+		if kind == reflect.Pointer || kind == reflect.Slice {
+			kind = field.Type.Elem().Kind()
+		}
+		switch kind {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			names = append(names, name)
+		}
 	}
-	return page, pageSize
+	return names
 }
@@ -4,71 +4,76 @@
 package main
 
 import (
-	"database/sql"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
-	"strings"
+	"strconv"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"pengyou-chinese/backend/internal/config"
+	"pengyou-chinese/backend/internal/service"
 )
 
-const dbName = "words.db"
+// cfg holds the settings read from the environment, see config.Load
+var cfg config.Config
 
-// SeedGroup represents a group in the seed file
-type SeedGroup struct {
-	Name string `json:"name"`
-}
-
-// SeedWord represents a word in the seed file
-type SeedWord struct {
-	Japanese string `json:"japanese"`
-	Romaji   string `json:"romaji"`
-	English  string `json:"english"`
-	Parts    string `json:"parts"`
-}
-
-// SeedFile represents the structure of a word group seed file
-type SeedFile struct {
-	Group SeedGroup  `json:"group"`
-	Words []SeedWord `json:"words"`
-}
-
-// ActivitySeedFile represents the structure of the activities seed file
-type ActivitySeedFile struct {
-	Activities []struct {
-		ID           int    `json:"id"`
-		Name         string `json:"name"`
-		Description  string `json:"description"`
-		ThumbnailURL string `json:"thumbnail_url"`
-	} `json:"activities"`
-}
+// dsn is the database selected by DATABASE_URL
+var dsn string
 
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Available commands:")
 		fmt.Println("  initdb   - Initialize the database")
 		fmt.Println("  migrate  - Run database migrations")
-		fmt.Println("  seed     - Seed the database with initial data")
-		fmt.Println("  clean    - Remove the database")
+		fmt.Println("  migrate:status - List migrations and whether they are applied")
+		fmt.Println("  migrate:down [steps] - Roll back the last migrations (default 1)")
+		fmt.Println("  seed [--dry-run] - Seed the database with initial data, or only show what would change")
+		fmt.Println("  clean    - Remove the database, or drop its tables on PostgreSQL")
 		fmt.Println("  reset    - Reset the database (clean + init + migrate)")
+		fmt.Println("  backup   - Write a snapshot of the SQLite database into BACKUP_DIR, keeping the last BACKUP_KEEP")
+		fmt.Println("  backup:schedule - Write a snapshot every BACKUP_INTERVAL until interrupted")
 		return
 	}
 
 	var err error
+	cfg, err = config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	dsn = cfg.DatabaseURL
+
 	switch os.Args[1] {
 	case "initdb":
 		err = InitDB()
 	case "migrate":
 		err = Migrate()
+	case "migrate:status":
+		err = MigrateStatus()
+	case "migrate:down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil {
+				fmt.Printf("Invalid number of steps: %s\n", os.Args[2])
+				os.Exit(1)
+			}
+		}
+		err = MigrateDown(steps)
 	case "seed":
-		err = Seed()
+		dryRun := len(os.Args) > 2 && os.Args[2] == "--dry-run"
+		if len(os.Args) > 2 && !dryRun {
+			fmt.Printf("Unknown option: %s\n", os.Args[2])
+			os.Exit(1)
+		}
+		err = Seed(dryRun)
 	case "clean":
 		err = Clean()
 	case "reset":
 		err = Reset()
+	case "backup":
+		err = Backup()
+	case "backup:schedule":
+		err = BackupSchedule()
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		os.Exit(1)
@@ -80,22 +85,28 @@ func main() {
 	}
 }
 
-// InitDB initializes the SQLite database
+// InitDB initializes the SQLite database. PostgreSQL databases are created by
+// the database administrator.
 func InitDB() error {
 	fmt.Println("Initializing database...")
 
-	if _, err := os.Stat(dbName); err == nil {
-		fmt.Printf("Database %s already exists\n", dbName)
+	if service.DialectOf(dsn) != service.SQLite {
+		fmt.Println("Nothing to initialize for PostgreSQL")
+		return nil
+	}
+
+	if _, err := os.Stat(dsn); err == nil {
+		fmt.Printf("Database %s already exists\n", dsn)
 		return nil
 	}
 
-	file, err := os.Create(dbName)
+	file, err := os.Create(dsn)
 	if err != nil {
 		return fmt.Errorf("error creating database file: %v", err)
 	}
 	file.Close()
 
-	fmt.Printf("Created database %s\n", dbName)
+	fmt.Printf("Created database %s\n", dsn)
 	return nil
 }
 
@@ -103,170 +114,133 @@ func InitDB() error {
 func Migrate() error {
 	fmt.Println("Running migrations...")
 
-	db, err := sql.Open("sqlite3", dbName)
+	db, err := service.OpenDB(dsn)
 	if err != nil {
-		return fmt.Errorf("error opening database: %v", err)
+		return err
 	}
 	defer db.Close()
 
-	// Get all migration files
-	files, err := filepath.Glob("db/migrations/*.sql")
-	if err != nil {
-		return fmt.Errorf("error finding migration files: %v", err)
-	}
-
-	// Sort migration files by name
-	sort.Strings(files)
-
-	for _, file := range files {
-		fmt.Printf("Applying migration %s...\n", filepath.Base(file))
-
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("error reading migration file %s: %v", file, err)
-		}
-
-		// Split the file into separate statements
-		statements := strings.Split(string(content), ";")
-
-		// Execute each statement
-		for _, stmt := range statements {
-			stmt = strings.TrimSpace(stmt)
-			if stmt == "" {
-				continue
-			}
-
-			_, err = db.Exec(stmt)
-			if err != nil {
-				return fmt.Errorf("error executing migration %s: %v", file, err)
-			}
-		}
+	if err := service.Migrate(db, os.Stdout); err != nil {
+		return err
 	}
 
 	fmt.Println("Migrations completed successfully")
 	return nil
 }
 
-// Seed imports data from JSON files in the seeds directory
-func Seed() error {
-	fmt.Println("Seeding database...")
-
-	db, err := sql.Open("sqlite3", dbName)
+// MigrateStatus lists the migrations and whether they have been applied
+func MigrateStatus() error {
+	db, err := service.OpenDB(dsn)
 	if err != nil {
-		return fmt.Errorf("error opening database: %v", err)
+		return err
 	}
 	defer db.Close()
 
-	// Get all seed files
-	files, err := filepath.Glob("db/seeds/*.json")
+	statuses, err := service.MigrationStatuses(db)
 	if err != nil {
-		return fmt.Errorf("error finding seed files: %v", err)
+		return err
 	}
 
-	// Process activities first
-	for _, file := range files {
-		if strings.Contains(file, "activities") {
-			if err := seedActivities(db, file); err != nil {
-				return err
-			}
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
 		}
-	}
-
-	// Then process word groups
-	for _, file := range files {
-		if !strings.Contains(file, "activities") {
-			if err := seedWordGroup(db, file); err != nil {
-				return err
-			}
+		if status.Modified {
+			state += " (modified since applied)"
 		}
+		if status.Missing {
+			state += " (missing from this build)"
+		}
+		if status.Unavailable != "" && status.AppliedAt == nil {
+			state = "skipped (needs -tags sqlite_" + status.Unavailable + ")"
+		}
+		fmt.Printf("%-40s %s\n", status.Version, state)
 	}
-
-	fmt.Println("Seeding completed successfully")
 	return nil
 }
 
-func seedActivities(db *sql.DB, file string) error {
-	content, err := os.ReadFile(file)
-	if err != nil {
-		return fmt.Errorf("error reading seed file %s: %v", file, err)
-	}
+// MigrateDown rolls back the given number of most recently applied migrations
+func MigrateDown(steps int) error {
+	fmt.Println("Rolling back migrations...")
 
-	var seedFile ActivitySeedFile
-	if err := json.Unmarshal(content, &seedFile); err != nil {
-		return fmt.Errorf("error parsing seed file %s: %v", file, err)
+	db, err := service.OpenDB(dsn)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	for _, activity := range seedFile.Activities {
-		_, err := db.Exec(`
-			INSERT INTO study_activities (id, study_session_id, group_id)
-			VALUES (?, 1, 1)
-		`, activity.ID)
-		if err != nil {
-			return fmt.Errorf("error inserting activity: %v", err)
-		}
+	if err := service.MigrateDown(db, steps, os.Stdout); err != nil {
+		return err
 	}
 
+	fmt.Println("Rollback completed successfully")
 	return nil
 }
 
-func seedWordGroup(db *sql.DB, file string) error {
-	content, err := os.ReadFile(file)
-	if err != nil {
-		return fmt.Errorf("error reading seed file %s: %v", file, err)
+// Seed imports data from JSON files in the seeds directory. Seeding again
+// only applies what changed in the seed files. With dryRun the changes are
+// listed without being written.
+func Seed(dryRun bool) error {
+	if dryRun {
+		fmt.Println("Seeding database (dry run, nothing is written)...")
+	} else {
+		fmt.Println("Seeding database...")
 	}
 
-	var seedFile SeedFile
-	if err := json.Unmarshal(content, &seedFile); err != nil {
-		return fmt.Errorf("error parsing seed file %s: %v", file, err)
+	db, err := service.OpenDB(dsn)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	// Insert group
-	result, err := db.Exec(`
-		INSERT INTO groups (name)
-		VALUES (?)
-	`, seedFile.Group.Name)
+	report, err := service.Seed(db, os.Stdout, dryRun)
 	if err != nil {
-		return fmt.Errorf("error inserting group: %v", err)
+		return err
 	}
 
-	groupID, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("error getting group ID: %v", err)
+	for _, kind := range []struct {
+		name   string
+		counts service.SeedCounts
+	}{
+		{"activities", report.Activities},
+		{"groups", report.Groups},
+		{"words", report.Words},
+		{"group words", report.GroupWords},
+	} {
+		fmt.Printf("%-12s %d created, %d updated, %d unchanged\n", kind.name+":", kind.counts.Created, kind.counts.Updated, kind.counts.Unchanged)
 	}
 
-	// Insert words and create word-group associations
-	for _, word := range seedFile.Words {
-		result, err := db.Exec(`
-			INSERT INTO words (japanese, romaji, english, parts)
-			VALUES (?, ?, ?, ?)
-		`, word.Japanese, word.Romaji, word.English, word.Parts)
-		if err != nil {
-			return fmt.Errorf("error inserting word: %v", err)
-		}
+	if dryRun {
+		fmt.Println("Dry run completed, no changes were written")
+	} else {
+		fmt.Println("Seeding completed successfully")
+	}
+	return nil
+}
 
-		wordID, err := result.LastInsertId()
+// Clean removes the SQLite database file, or drops every table of a PostgreSQL database
+func Clean() error {
+	if service.DialectOf(dsn) != service.SQLite {
+		fmt.Println("Dropping database tables...")
+
+		db, err := service.OpenDB(dsn)
 		if err != nil {
-			return fmt.Errorf("error getting word ID: %v", err)
+			return err
 		}
+		defer db.Close()
 
-		_, err = db.Exec(`
-			INSERT INTO words_groups (word_id, group_id)
-			VALUES (?, ?)
-		`, wordID, groupID)
-		if err != nil {
-			return fmt.Errorf("error inserting word-group association: %v", err)
+		if err := service.DropTables(db); err != nil {
+			return err
 		}
-	}
 
-	fmt.Printf("Seeded group '%s' with %d words\n", seedFile.Group.Name, len(seedFile.Words))
-	return nil
-}
+		fmt.Println("Database cleaned successfully")
+		return nil
+	}
 
-// Clean removes the database file
-func Clean() error {
-	fmt.Printf("Removing database %s...\n", dbName)
+	fmt.Printf("Removing database %s...\n", dsn)
 
-	err := os.Remove(dbName)
+	err := os.Remove(dsn)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error removing database: %v", err)
 	}
@@ -288,3 +262,53 @@ func Reset() error {
 	}
 	return nil
 }
+
+// Backup writes a snapshot of the SQLite database with SQLite's online backup
+// API, which is safe while the server is running, and removes the oldest
+// snapshots beyond BACKUP_KEEP
+func Backup() error {
+	if dsn == service.MemoryDSN || service.DialectOf(dsn) != service.SQLite {
+		return fmt.Errorf("backups are only supported for SQLite databases, use the database's own tools such as pg_dump")
+	}
+	if _, err := os.Stat(dsn); err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+
+	db, err := service.OpenDB(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshots := service.Snapshots{Dir: cfg.BackupDir, Prefix: service.SnapshotPrefix(dsn), Keep: cfg.BackupKeep}
+	snapshot, err := snapshots.Take(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backed up %s to %s (%d bytes)\n", dsn, snapshot.Path, snapshot.SizeBytes)
+	for _, file := range snapshot.Removed {
+		fmt.Printf("Removed old snapshot %s\n", file)
+	}
+	return nil
+}
+
+// BackupSchedule runs Backup every BACKUP_INTERVAL until the task is stopped.
+// Failed backups are reported and retried at the next interval.
+func BackupSchedule() error {
+	if cfg.BackupInterval <= 0 {
+		return fmt.Errorf("BACKUP_INTERVAL must be set to the time between backups, such as 6h")
+	}
+
+	retention := "keeping all snapshots"
+	if cfg.BackupKeep > 0 {
+		retention = fmt.Sprintf("keeping the last %d snapshots", cfg.BackupKeep)
+	}
+	fmt.Printf("Backing up every %s, %s in %s\n", cfg.BackupInterval, retention, cfg.BackupDir)
+	for {
+		if err := Backup(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		time.Sleep(cfg.BackupInterval)
+	}
+}